@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+)
+
+// arrowWriter writes the records unchanged to an Arrow IPC file.
+type arrowWriter struct {
+	w  io.Writer
+	fw *ipc.FileWriter
+	// schema is the one announced by the server, for writing empty results
+	schema *arrow.Schema
+}
+
+func newArrowWriter(w io.Writer) *arrowWriter {
+	return &arrowWriter{w: w}
+}
+
+func (a *arrowWriter) announceSchema(schema *arrow.Schema) {
+	a.schema = schema
+}
+
+func (a *arrowWriter) WriteRecord(record arrow.Record) error {
+	if a.fw == nil {
+		if err := a.open(record.Schema()); err != nil {
+			return err
+		}
+	}
+	return a.fw.Write(record)
+}
+
+func (a *arrowWriter) open(schema *arrow.Schema) error {
+	// the footer of an Arrow file points back to the record batches, so the output must be seekable
+	ws, ok := a.w.(io.WriteSeeker)
+	if !ok {
+		return fmt.Errorf("arrow output requires a seekable output file")
+	}
+	fw, err := ipc.NewFileWriter(ws, ipc.WithSchema(schema))
+	if err != nil {
+		return err
+	}
+	a.fw = fw
+	return nil
+}
+
+// Close completes the file. Without any records, it's written with the announced schema, or no columns
+// if the server didn't tell, so that it's still a valid Arrow file.
+func (a *arrowWriter) Close() error {
+	if a.fw == nil {
+		schema := a.schema
+		if schema == nil {
+			schema = arrow.NewSchema(nil, nil)
+		}
+		if err := a.open(schema); err != nil {
+			return err
+		}
+	}
+	return a.fw.Close()
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	"google.golang.org/grpc/metadata"
+)
+
+// artifacts is the directory where a single invocation stores everything needed to reproduce it:
+//
+//	query.sql       the query text
+//	run.json        run ID, server, database, version, timings and error
+//	schema.txt      the schema of the results
+//	trace_ids.txt   the trace IDs sent to the server, if any
+//	results.arrows  the raw results as an Arrow IPC stream (only with --artifacts-raw)
+//
+// The statements of a multi-statement run, whose results have schemas of their own, are saved in
+// schema-N.txt and results-N.arrows instead, N being the number of the statement.
+type artifacts struct {
+	RunID   string
+	Dir     string
+	started time.Time
+
+	raw     bool
+	schemas map[string]bool
+	streams map[string]*rawStream
+}
+
+// rawStream is a raw Arrow IPC stream of results being saved.
+type rawStream struct {
+	f *os.File
+	w *ipc.Writer
+}
+
+// newArtifacts creates a directory named after a new unique run ID under dir.
+func newArtifacts(dir string, raw bool) (*artifacts, error) {
+	started := time.Now()
+	runID := fmt.Sprintf("%s-%s", started.UTC().Format("20060102T150405Z"), generateRandomHex(4))
+	a := &artifacts{RunID: runID, Dir: filepath.Join(dir, runID), started: started, raw: raw,
+		schemas: map[string]bool{}, streams: map[string]*rawStream{}}
+	if err := os.MkdirAll(a.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *artifacts) path(name string) string {
+	return filepath.Join(a.Dir, name)
+}
+
+func (a *artifacts) writeQuery(query string) error {
+	return os.WriteFile(a.path("query.sql"), []byte(strings.TrimSpace(query)+"\n"), 0o644)
+}
+
+// writeTraceIDs records the trace IDs carried by the outgoing metadata of ctx.
+func (a *artifacts) writeTraceIDs(ctx context.Context) error {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	var lines []string
+	for _, h := range []string{traceIDHeader, traceIDHeader2, traceparentHeader, tracestateHeader} {
+		for _, v := range md.Get(h) {
+			lines = append(lines, fmt.Sprintf("%s: %s\n", h, v))
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return os.WriteFile(a.path("trace_ids.txt"), []byte(strings.Join(lines, "")), 0o644)
+}
+
+// writeRecord saves the schema of the results when the first record is received
+// and, if enabled, appends the record to the raw Arrow stream.
+func (a *artifacts) writeRecord(record arrow.Record) error {
+	return a.write("schema.txt", "results.arrows", record)
+}
+
+// writeStatementRecord is writeRecord for the results of the stmt-th (zero based) statement of a multi-statement run.
+func (a *artifacts) writeStatementRecord(stmt int, record arrow.Record) error {
+	return a.write(fmt.Sprintf("schema-%d.txt", stmt+1), fmt.Sprintf("results-%d.arrows", stmt+1), record)
+}
+
+func (a *artifacts) write(schemaName, rawName string, record arrow.Record) error {
+	if !a.schemas[schemaName] {
+		if err := os.WriteFile(a.path(schemaName), []byte(record.Schema().String()+"\n"), 0o644); err != nil {
+			return err
+		}
+		a.schemas[schemaName] = true
+	}
+	if !a.raw {
+		return nil
+	}
+	s := a.streams[rawName]
+	if s == nil {
+		f, err := os.Create(a.path(rawName))
+		if err != nil {
+			return err
+		}
+		s = &rawStream{f: f, w: ipc.NewWriter(f, ipc.WithSchema(record.Schema()))}
+		a.streams[rawName] = s
+	}
+	return s.w.Write(record)
+}
+
+// finish writes the run summary and closes the raw Arrow streams.
+func (a *artifacts) finish(cli *Context, timings Timings, runErr error) error {
+	for _, s := range a.streams {
+		if err := s.w.Close(); err != nil {
+			return err
+		}
+		if err := s.f.Close(); err != nil {
+			return err
+		}
+	}
+
+	summary := struct {
+		RunID   string    `json:"run_id"`
+		Started time.Time `json:"started"`
+		URL     string    `json:"url"`
+		DB      string    `json:"db"`
+		Version string    `json:"version"`
+		Warmup  float64   `json:"warmup_seconds"`
+		Execute float64   `json:"execute_seconds"`
+		DoGet   float64   `json:"doget_seconds"`
+		Total   float64   `json:"total_seconds"`
+		Error   string    `json:"error,omitempty"`
+	}{
+		RunID:   a.RunID,
+		Started: a.started,
+		URL:     cli.URL,
+		DB:      cli.DB,
+		Version: getVersion(),
+		Warmup:  timings.Warmup.Seconds(),
+		Execute: timings.Execute.Seconds(),
+		DoGet:   timings.DoGet.Seconds(),
+		Total:   timings.Total().Seconds(),
+	}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(a.path("run.json"), append(b, '\n'), 0o644)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/user"
+	"time"
+)
+
+// auditLog appends a JSON line per invocation to the file set by audit_log in the config file, e.g.:
+//
+//	{"time":"2024-01-02T15:04:05Z","user":"alice","host":"laptop","url":"https://prod.example.com","db":"telemetry",
+//	 "command":"query <query>","query":"SELECT ...","duration_seconds":1.2,"rows":100,"status":"ok"}
+//
+// Credentials are never recorded.
+type auditLog struct {
+	f       *os.File
+	started time.Time
+	command string
+	query   string
+	// counts are the results of the last attempt of the query, if any
+	counts *resultCounter
+}
+
+// openAuditLog opens the audit log before running the command, so that an unwritable log
+// fails the invocation up front rather than after querying the server.
+func openAuditLog(path, command string) (*auditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLog{f: f, started: time.Now(), command: command}, nil
+}
+
+func (a *auditLog) setQuery(query string) {
+	if a != nil {
+		a.query = query
+	}
+}
+
+func (a *auditLog) setCounts(counts *resultCounter) {
+	if a != nil {
+		a.counts = counts
+	}
+}
+
+// write appends the entry of the invocation, which ended with err, and closes the log.
+func (a *auditLog) write(cli *CLI, err error) error {
+	if a == nil {
+		return nil
+	}
+	defer a.f.Close()
+
+	entry := struct {
+		Time       time.Time `json:"time"`
+		User       string    `json:"user"`
+		FlightUser string    `json:"flight_user,omitempty"`
+		Host       string    `json:"host"`
+		URL        string    `json:"url"`
+		DB         string    `json:"db"`
+		Profile    string    `json:"profile,omitempty"`
+		Command    string    `json:"command"`
+		Query      string    `json:"query,omitempty"`
+		Duration   float64   `json:"duration_seconds"`
+		Rows       *int64    `json:"rows,omitempty"`
+		Status     string    `json:"status"`
+		Error      string    `json:"error,omitempty"`
+	}{
+		Time:       a.started.UTC(),
+		FlightUser: cli.Username,
+		URL:        cli.URL,
+		DB:         cli.DB,
+		Profile:    cli.Profile,
+		Command:    a.command,
+		Query:      a.query,
+		Duration:   time.Since(a.started).Seconds(),
+		Status:     "ok",
+	}
+	if u, err := user.Current(); err == nil {
+		entry.User = u.Username
+	}
+	entry.Host, _ = os.Hostname()
+	if a.counts != nil {
+		entry.Rows = &a.counts.rows
+	}
+	if err != nil {
+		entry.Status, entry.Error = "error", err.Error()
+	}
+	enc := json.NewEncoder(a.f)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return err
+	}
+	return a.f.Close()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	pb "github.com/apache/arrow/go/v15/arrow/flight/gen/flight"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// handshake logs in with --username and --password. Servers implementing the standard Flight basic auth
+// answer the Handshake with a bearer token in the authorization header, which then replaces our
+// authorization header on every call. Servers only implementing the older payload based handshake
+// return a token in the response payload instead (see Authenticate), sent back in the auth-token-bin header.
+func (cli *CLI) handshake(ctx context.Context, c *flightsql.Client) error {
+	// drop the authorization header we'd normally send, the handshake carries the credentials
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Delete("authorization")
+	authCtx, err := c.Client.AuthenticateBasicToken(metadata.NewOutgoingContext(ctx, md), cli.Username, cli.Password)
+	if err == nil {
+		authMD, _ := metadata.FromOutgoingContext(authCtx)
+		tokens := authMD.Get("authorization")
+		cli.bearer = tokens[len(tokens)-1]
+		return nil
+	}
+	if !strings.Contains(err.Error(), "no authorization header") {
+		return fmt.Errorf("handshake: %w", err)
+	}
+	if err := c.Client.Authenticate(metadata.NewOutgoingContext(ctx, md)); err != nil {
+		return fmt.Errorf("handshake: %w", err)
+	}
+	return nil
+}
+
+// Authenticate performs the payload based handshake: it sends the username and password
+// as a BasicAuth message and keeps the token the server answers with.
+func (cli *CLI) Authenticate(ctx context.Context, conn flight.AuthConn) error {
+	payload, err := proto.Marshal(&pb.BasicAuth{Username: cli.Username, Password: cli.Password})
+	if err != nil {
+		return err
+	}
+	if err := conn.Send(payload); err != nil {
+		return err
+	}
+	token, err := conn.Read()
+	if err != nil {
+		return err
+	}
+	cli.handshakeToken = string(token)
+	return nil
+}
+
+// GetToken returns the token sent in the auth-token-bin header of every call.
+func (cli *CLI) GetToken(context.Context) (string, error) {
+	if cli.handshakeToken != "" {
+		return cli.handshakeToken, nil
+	}
+	if cli.OAuthTokenURL != "" {
+		return cli.oauthAccessToken()
+	}
+	return cli.accessToken(), nil
+}
+
+// StartCall replaces the authorization header with the bearer token obtained by the handshake
+// or from the OAuth token endpoint, if any, and adds the sticky routing headers captured so far.
+func (cli *CLI) StartCall(ctx context.Context) context.Context {
+	ctx = cli.withStickyHeaders(ctx)
+	bearer := cli.bearer
+	if bearer == "" && cli.OAuthTokenURL != "" {
+		token, err := cli.oauthAccessToken()
+		if err != nil {
+			// GetToken reports the error when the call is authenticated
+			return ctx
+		}
+		bearer = "Bearer " + token
+	}
+	if bearer == "" {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set("authorization", bearer)
+	return metadata.NewOutgoingContext(ctx, md)
+}
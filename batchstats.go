@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/util"
+)
+
+// smallBatchRows is the number of rows below which a record batch is considered tiny:
+// per-batch overhead dominates the transfer of such batches.
+const smallBatchRows = 1024
+
+// batchStats collects the size of the record batches received from DoGet, for --batch-stats.
+// A nil batchStats collects nothing.
+type batchStats struct {
+	mu    sync.Mutex
+	rows  []int64
+	bytes []int64
+}
+
+func (b *batchStats) add(record arrow.Record) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.rows = append(b.rows, record.NumRows())
+	b.bytes = append(b.bytes, util.TotalRecordSize(record))
+}
+
+func (b *batchStats) print(w io.Writer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.rows) == 0 {
+		fmt.Fprintln(w, "\nNo record batches received")
+		return
+	}
+
+	fmt.Fprintln(w)
+	table := newStatsTableTo(w, "Per batch", "Min", "Mean", "P50", "P95", "Max")
+	for _, s := range []struct {
+		name    string
+		samples []int64
+	}{
+		{"Rows", b.rows},
+		{"Bytes", b.bytes},
+	} {
+		sorted := append([]int64(nil), s.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var sum int64
+		for _, n := range sorted {
+			sum += n
+		}
+		table.Append([]string{s.name,
+			strconv.FormatInt(sorted[0], 10),
+			strconv.FormatInt(sum/int64(len(sorted)), 10),
+			strconv.FormatInt(sorted[(len(sorted)-1)/2], 10),
+			strconv.FormatInt(sorted[(len(sorted)*95+99)/100-1], 10),
+			strconv.FormatInt(sorted[len(sorted)-1], 10),
+		})
+	}
+	table.Render()
+
+	small := 0
+	for _, n := range b.rows {
+		if n < smallBatchRows {
+			small++
+		}
+	}
+	fmt.Fprintf(w, "\n%d batches, %d with fewer than %d rows\n", len(b.rows), small, smallBatchRows)
+}
+
+type batchStatsKey struct{}
+
+func withBatchStats(ctx context.Context, b *batchStats) context.Context {
+	return context.WithValue(ctx, batchStatsKey{}, b)
+}
+
+// batchStatsFrom returns the batch statistics collector of ctx, or nil if --batch-stats isn't set.
+func batchStatsFrom(ctx context.Context) *batchStats {
+	b, _ := ctx.Value(batchStatsKey{}).(*batchStats)
+	return b
+}
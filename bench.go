@@ -0,0 +1,243 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/olekukonko/tablewriter"
+)
+
+// BenchCmd groups the benchmarking modes.
+type BenchCmd struct {
+	Run     BenchRunCmd     `cmd:"" default:"withargs" help:"Run a query repeatedly and report timing statistics (default)"`
+	Compare BenchCompareCmd `cmd:"" help:"Run a query against two servers, interleaved, and compare their timings"`
+}
+
+// BenchRunCmd runs the same query repeatedly and reports timing statistics.
+type BenchRunCmd struct {
+	Query    string `arg:"" help:"Query text"`
+	Count    int    `short:"n" default:"10" help:"Number of measured runs"`
+	ColdWarm bool   `help:"Alternate freshly dialed (cold) runs with reused-connection (warm) runs and compare the two"`
+
+	Ramp rampSchedule `help:"Load mode: step concurrency up over time (e.g. 1:30s,10:60s,50:120s) and report the latency/error knee"`
+
+	Concurrency int           `help:"Load mode: run the query from this many goroutines at once and report a latency histogram and the error rate"`
+	Duration    time.Duration `default:"30s" help:"How long the --concurrency load test runs"`
+	Rps         float64       `help:"Cap the --concurrency load test to this many requests per second in total"`
+	Connections int           `default:"1" help:"Number of gRPC connections the --concurrency load test spreads its goroutines over"`
+
+	Fanout int `help:"Contrast fetching the partitions of one query in parallel with running independent queries in parallel, doubling the parallelism up to this value (-n runs per level)"`
+
+	Export       string `help:"Write the full result set to this file"`
+	ExportFormat string `enum:"auto,json,openmetrics" default:"auto" help:"Format of the --export file (auto picks by extension: .json or OpenMetrics text)"`
+	Report       string `help:"Write an HTML report with latency distribution and throughput charts to this file"`
+}
+
+// benchSamples collects the per-phase durations of a series of runs.
+type benchSamples struct {
+	Execute []time.Duration
+	DoGet   []time.Duration
+	Total   []time.Duration
+	Rows    []int64
+}
+
+func (s *benchSamples) add(rows int64, t Timings) {
+	s.Execute = append(s.Execute, t.Execute)
+	s.DoGet = append(s.DoGet, t.DoGet)
+	s.Total = append(s.Total, t.Total())
+	s.Rows = append(s.Rows, rows)
+}
+
+// throughput returns the average number of rows per run and the rows received per second of total query time.
+func (s benchSamples) throughput() (rowsPerRun, rowsPerSec float64) {
+	var (
+		rows  int64
+		total time.Duration
+	)
+	for i := range s.Rows {
+		rows += s.Rows[i]
+		total += s.Total[i]
+	}
+	if len(s.Rows) == 0 || total == 0 {
+		return 0, 0
+	}
+	return float64(rows) / float64(len(s.Rows)), float64(rows) / total.Seconds()
+}
+
+func (cmd *BenchRunCmd) Run(cli *Context) error {
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// the first request on a connection pays for connection setup, keep it out of the warm samples
+	if _, _, err := runQuery(ctx, c, cmd.Query); err != nil {
+		return err
+	}
+
+	report := benchReport{Query: cmd.Query, URL: cli.URL, DB: cli.DB, Started: time.Now()}
+	switch {
+	case len(cmd.Ramp) > 0:
+		report.addSteps(runRamp(ctx, c, cmd.Query, cmd.Ramp))
+	case cmd.Fanout > 0:
+		if err := runFanout(ctx, c, cmd.Query, cmd.Fanout, cmd.Count); err != nil {
+			return err
+		}
+	case cmd.Concurrency > 0:
+		res, err := cmd.runLoadTest(ctx, cli, c)
+		if err != nil {
+			return err
+		}
+		report.addSteps([]loadResult{res})
+	default:
+		cold, warm, err := cmd.runSeries(ctx, cli, c)
+		if err != nil {
+			return err
+		}
+		report.addRuns("cold", cold)
+		report.addRuns("warm", warm)
+	}
+
+	if cmd.Export != "" {
+		if err := report.export(cmd.Export, cmd.ExportFormat); err != nil {
+			return err
+		}
+	}
+	if cmd.Report != "" {
+		return report.writeHTMLReport(cmd.Report)
+	}
+	return nil
+}
+
+// runLoadTest drives the query at the requested concurrency and rate over --connections connections
+// (c and freshly dialed ones) and prints the outcome.
+func (cmd *BenchRunCmd) runLoadTest(ctx context.Context, cli *Context, c *flightsql.Client) (loadResult, error) {
+	clients := []*flightsql.Client{c}
+	for len(clients) < cmd.Connections {
+		extra, err := cli.dial(ctx)
+		if err != nil {
+			return loadResult{}, err
+		}
+		defer extra.Close()
+		if _, _, err := runQuery(ctx, extra, cmd.Query); err != nil {
+			return loadResult{}, err
+		}
+		clients = append(clients, extra)
+	}
+
+	res := runLoad(ctx, clients, cmd.Query, cmd.Concurrency, cmd.Duration, cmd.Rps)
+	printLoad(res)
+	return res, nil
+}
+
+// runSeries runs the measured iterations and prints their statistics.
+func (cmd *BenchRunCmd) runSeries(ctx context.Context, cli *Context, c *flightsql.Client) (cold, warm benchSamples, err error) {
+	for i := 0; i < cmd.Count; i++ {
+		if cmd.ColdWarm {
+			rows, t, err := cmd.coldRun(ctx, cli)
+			if err != nil {
+				return cold, warm, err
+			}
+			cold.add(rows, t)
+		}
+
+		rows, t, err := runQuery(ctx, c, cmd.Query)
+		if err != nil {
+			return cold, warm, err
+		}
+		warm.add(rows, t)
+	}
+
+	if cmd.ColdWarm {
+		printColdWarm(cold, warm)
+	} else {
+		printBench(warm)
+	}
+	return cold, warm, nil
+}
+
+// coldRun dials a fresh connection, so the timings include connection and TLS setup.
+func (cmd *BenchRunCmd) coldRun(ctx context.Context, cli *Context) (int64, Timings, error) {
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	defer c.Close()
+	return runQuery(ctx, c, cmd.Query)
+}
+
+func printBench(s benchSamples) {
+	table := newStatsTable("Phase", "Min", "Mean", "P50", "P95", "P99", "Max")
+	for _, p := range []struct {
+		name    string
+		samples []time.Duration
+	}{
+		{"Execute", s.Execute},
+		{"DoGet", s.DoGet},
+		{"Total", s.Total},
+	} {
+		st := newDurationStats(p.samples)
+		table.Append([]string{p.name,
+			st.Min.String(), st.Mean.String(), st.P50.String(), st.P95.String(), st.P99.String(), st.Max.String()})
+	}
+	table.Render()
+
+	rowsPerRun, rowsPerSec := s.throughput()
+	fmt.Printf("\n%d runs, %.0f rows/run, %.1f rows/sec\n", len(s.Total), rowsPerRun, rowsPerSec)
+}
+
+func printColdWarm(cold, warm benchSamples) {
+	table := newStatsTable("Phase", "Stat", "Cold", "Warm", "Delta")
+	for _, p := range []struct {
+		name       string
+		cold, warm []time.Duration
+	}{
+		{"Execute", cold.Execute, warm.Execute},
+		{"DoGet", cold.DoGet, warm.DoGet},
+		{"Total", cold.Total, warm.Total},
+	} {
+		c, w := newDurationStats(p.cold), newDurationStats(p.warm)
+		for _, st := range []struct {
+			name       string
+			cold, warm time.Duration
+		}{
+			{"min", c.Min, w.Min},
+			{"mean", c.Mean, w.Mean},
+			{"p50", c.P50, w.P50},
+			{"p95", c.P95, w.P95},
+			{"max", c.Max, w.Max},
+		} {
+			table.Append([]string{p.name, st.name, st.cold.String(), st.warm.String(), (st.cold - st.warm).String()})
+		}
+	}
+	table.Render()
+
+	worth := newDurationStats(cold.Total).Mean - newDurationStats(warm.Total).Mean
+	fmt.Printf("\nWarmup is worth %s per query (mean cold total - mean warm total)\n", worth)
+	_, coldRate := cold.throughput()
+	_, warmRate := warm.throughput()
+	fmt.Printf("Throughput: %.1f rows/sec cold, %.1f rows/sec warm\n", coldRate, warmRate)
+}
+
+func newStatsTable(header ...string) *tablewriter.Table {
+	return newStatsTableTo(os.Stdout, header...)
+}
+
+func newStatsTableTo(w io.Writer, header ...string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetAutoFormatHeaders(false)
+	table.SetBorder(false)
+	table.SetAlignment(tablewriter.ALIGN_RIGHT)
+	table.SetHeader(header)
+	return table
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// BenchCompareCmd runs the same query against two servers, alternating between them, and compares the timings.
+type BenchCompareCmd struct {
+	Query string `arg:"" help:"Query text"`
+	URLA  string `name:"url-a" help:"URL of the first (baseline) server (default: --url)"`
+	URLB  string `name:"url-b" required:"" help:"URL of the second server"`
+	Count int    `short:"n" default:"10" help:"Number of measured runs against each server"`
+}
+
+func (cmd *BenchCompareCmd) Run(cli *Context) error {
+	if cmd.URLA == "" {
+		cmd.URLA = cli.URL
+	}
+	ctx, a, err := cmd.connect(cli, cmd.URLA)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.URLA, err)
+	}
+	defer a.Close()
+	defer closeReferrals(ctx)
+	ctxB, b, err := cmd.connect(cli, cmd.URLB)
+	if err != nil {
+		return fmt.Errorf("%s: %w", cmd.URLB, err)
+	}
+	defer b.Close()
+	defer closeReferrals(ctxB)
+
+	var samplesA, samplesB benchSamples
+	for i := 0; i < cmd.Count; i++ {
+		// alternate which server goes first, so neither consistently benefits from the other's side effects
+		first, second := func() error { return benchOnce(ctx, a, cmd.Query, &samplesA) }, func() error { return benchOnce(ctxB, b, cmd.Query, &samplesB) }
+		if i%2 == 1 {
+			first, second = second, first
+		}
+		if err := first(); err != nil {
+			return err
+		}
+		if err := second(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("A: %s\nB: %s\n\n", cmd.URLA, cmd.URLB)
+	printCompare(samplesA, samplesB)
+	return nil
+}
+
+// connect dials the server at url with the global settings, and warms up the connection.
+func (cmd *BenchCompareCmd) connect(cli *Context, url string) (context.Context, *flightsql.Client, error) {
+	target := *cli.CLI
+	target.URL = url
+	target.sticky = target.newStickyHeaders()
+	ctx, err := target.newContext()
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := target.dial(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, _, err := runQuery(ctx, c, cmd.Query); err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return ctx, c, nil
+}
+
+func benchOnce(ctx context.Context, c *flightsql.Client, query string, samples *benchSamples) error {
+	rows, t, err := runQuery(ctx, c, query)
+	if err != nil {
+		return err
+	}
+	samples.add(rows, t)
+	return nil
+}
+
+func printCompare(a, b benchSamples) {
+	table := newStatsTable("Phase", "A mean", "A stddev", "B mean", "B stddev", "Delta", "Delta %", "t", "Significant")
+	for _, p := range []struct {
+		name string
+		a, b []time.Duration
+	}{
+		{"Execute", a.Execute, b.Execute},
+		{"DoGet", a.DoGet, b.DoGet},
+		{"Total", a.Total, b.Total},
+	} {
+		sa, sb := newDurationStats(p.a), newDurationStats(p.b)
+		delta := sb.Mean - sa.Mean
+		relative := "-"
+		if sa.Mean > 0 {
+			relative = fmt.Sprintf("%+.1f%%", 100*float64(delta)/float64(sa.Mean))
+		}
+		t := welchT(sa, sb)
+		significant := "no"
+		if math.Abs(t) > 1.96 {
+			significant = "yes"
+		}
+		table.Append([]string{p.name,
+			sa.Mean.String(), sa.Stddev.String(), sb.Mean.String(), sb.Stddev.String(),
+			delta.String(), relative, fmt.Sprintf("%.2f", t), significant})
+	}
+	table.Render()
+
+	_, rateA := a.throughput()
+	_, rateB := b.throughput()
+	fmt.Printf("\nThroughput: %.1f rows/sec on A, %.1f rows/sec on B\n", rateA, rateB)
+	fmt.Println("Significant means |t| > 1.96 (Welch's t-test, about 95% confidence with enough runs)")
+}
+
+// welchT returns Welch's t statistic for the difference between the means of b and a.
+func welchT(a, b durationStats) float64 {
+	if a.N < 2 || b.N < 2 {
+		return 0
+	}
+	// durationStats has the population stddev, correct it to the sample variance
+	va := float64(a.Stddev) * float64(a.Stddev) * float64(a.N) / float64(a.N-1)
+	vb := float64(b.Stddev) * float64(b.Stddev) * float64(b.N) / float64(b.N-1)
+	se := math.Sqrt(va/float64(a.N) + vb/float64(b.N))
+	if se == 0 {
+		return 0
+	}
+	return float64(b.Mean-a.Mean) / se
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/util"
+	"golang.org/x/term"
+)
+
+// byteSize is a number of bytes parsed from a human friendly string like "100MB" or "1GiB".
+type byteSize int64
+
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+	{"B", 1},
+}
+
+func (b *byteSize) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	mult := int64(1)
+	for _, u := range byteUnits {
+		if strings.HasSuffix(strings.ToUpper(s), strings.ToUpper(u.suffix)) {
+			s, mult = strings.TrimSpace(s[:len(s)-len(u.suffix)]), u.size
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("invalid size %q", text)
+	}
+	*b = byteSize(n * float64(mult))
+	return nil
+}
+
+func (b byteSize) String() string {
+	if b < 1<<10 {
+		return fmt.Sprintf("%dB", int64(b))
+	}
+	for _, u := range byteUnits[:4] {
+		if int64(b) < u.size<<10 {
+			return fmt.Sprintf("%.1f%s", float64(b)/float64(u.size), u.suffix)
+		}
+	}
+	return fmt.Sprintf("%.1fTiB", float64(b)/float64(1<<40))
+}
+
+// byteBudget guards against fetching more data than expected.
+type byteBudget struct {
+	max      byteSize
+	received byteSize
+}
+
+// add accounts for a received record. Once the budget is exceeded it asks the user whether to continue
+// if stdin is a terminal, and fails otherwise.
+func (b *byteBudget) add(record arrow.Record) error {
+	if b.max <= 0 {
+		return nil
+	}
+	b.received += byteSize(util.TotalRecordSize(record))
+	if b.received <= b.max {
+		return nil
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "Received %s, exceeding the --max-bytes budget of %s. Continue? [y/N] ", b.received, b.max)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.HasPrefix(strings.ToLower(strings.TrimSpace(answer)), "y") {
+			b.max = 0
+			return nil
+		}
+	}
+	return fmt.Errorf("aborted after receiving %s, exceeding the --max-bytes budget of %s", b.received, b.max)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// cancelTimeout bounds the CancelFlightInfo request sent once the query context is done.
+const cancelTimeout = 5 * time.Second
+
+// interruptible returns a context canceled by the first SIGINT. A second SIGINT kills the process as usual,
+// for when the first one can't be honored promptly (e.g. while waiting for input).
+func interruptible(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		defer signal.Stop(sig)
+		select {
+		case <-sig:
+			fmt.Fprintln(os.Stderr, "\nInterrupted, cancelling the query (press Ctrl-C again to exit immediately)")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// cancelOnServer asks the server to stop working on info, when the query was abandoned because ctx is done
+// (interrupted, timed out or enough rows received). Otherwise the server would keep running the query
+// after we stopped reading.
+func cancelOnServer(ctx context.Context, c *flightsql.Client, info *flight.FlightInfo) {
+	if ctx.Err() == nil {
+		return
+	}
+	// ctx is done: send the request with a fresh context carrying the same headers
+	md, _ := metadata.FromOutgoingContext(ctx)
+	cancelCtx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), md), cancelTimeout)
+	defer cancel()
+
+	res, err := c.CancelFlightInfo(cancelCtx, &flight.CancelFlightInfoRequest{Info: info})
+	if errors.Is(context.Cause(ctx), errMaxRows) {
+		// we just don't need more rows, the query didn't fail
+		return
+	}
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		fmt.Fprintln(os.Stderr, "The server doesn't support cancelling queries, it may keep running")
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Cancelling the query on the server failed: %v\n", err)
+	case res.Status == flight.CancelStatusCancelled || res.Status == flight.CancelStatusCancelling:
+		fmt.Fprintln(os.Stderr, "Cancelled the query on the server")
+	default:
+		fmt.Fprintf(os.Stderr, "Cancelling the query on the server: %s\n", res.Status)
+	}
+}
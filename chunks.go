@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// exportManifest describes a chunked export, so that it can be resumed if interrupted, e.g.:
+//
+//	{
+//	  "query": "SELECT * FROM cpu ORDER BY time",
+//	  "output": "cpu.parquet",
+//	  "format": "parquet",
+//	  "chunk_rows": 1000000,
+//	  "chunks": [{"file": "cpu-000001.parquet", "first_row": 0, "rows": 1000000, "sha256": "..."}, ...],
+//	  "complete": false
+//	}
+type exportManifest struct {
+	Query     string        `json:"query"`
+	Output    string        `json:"output"`
+	Format    string        `json:"format"`
+	ChunkRows int           `json:"chunk_rows"`
+	Chunks    []exportChunk `json:"chunks"`
+	Complete  bool          `json:"complete"`
+}
+
+// exportChunk is a file holding the rows [FirstRow, FirstRow+Rows) of the results.
+type exportChunk struct {
+	File     string `json:"file"`
+	FirstRow int64  `json:"first_row"`
+	Rows     int64  `json:"rows"`
+	SHA256   string `json:"sha256"`
+}
+
+// manifestPath returns the default path of the manifest of an export to output.
+func manifestPath(output string) string {
+	return output + ".manifest.json"
+}
+
+// chunkFile returns the name of the n-th (one based) chunk of output: out-000001.csv for out.csv.
+func chunkFile(output string, n int) string {
+	ext := filepath.Ext(output)
+	return fmt.Sprintf("%s-%06d%s", strings.TrimSuffix(output, ext), n, ext)
+}
+
+func loadManifest(filename string) (*exportManifest, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var m exportManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+	return &m, nil
+}
+
+// save writes the manifest atomically, so that an interruption never leaves a truncated manifest behind.
+func (m *exportManifest) save(filename string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// verify drops the chunks whose file is missing or doesn't match its checksum, and all the following ones.
+func (m *exportManifest) verify() {
+	for i, chunk := range m.Chunks {
+		sum, err := fileSHA256(chunk.File)
+		if err != nil || sum != chunk.SHA256 {
+			fmt.Fprintf(os.Stderr, "Chunk %s is missing or corrupt, resuming from row %d\n", chunk.File, chunk.FirstRow)
+			m.Chunks, m.Complete = m.Chunks[:i], false
+			return
+		}
+	}
+}
+
+func fileSHA256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// runChunkedExport writes the results of query to files of chunkRows rows each, fetching each chunk with
+// a LIMIT/OFFSET query, and records the chunks written in a manifest. Given the manifest of an interrupted
+// export, it verifies the chunks already written and continues after the last one.
+// The query should have an ORDER BY, or the chunks may overlap.
+func runChunkedExport(ctx context.Context, c *flightsql.Client, query, output string, opts printOptions, chunkRows int, resume string) (Timings, error) {
+	var (
+		m        *exportManifest
+		manifest string
+	)
+	if resume != "" {
+		var err error
+		if m, err = loadManifest(resume); err != nil {
+			return Timings{}, err
+		}
+		switch {
+		case m.Query != query:
+			return Timings{}, fmt.Errorf("%s is the manifest of a different query", resume)
+		case output != "" && output != m.Output:
+			return Timings{}, fmt.Errorf("%s is the manifest of the export to %s", resume, m.Output)
+		case chunkRows > 0 && chunkRows != m.ChunkRows:
+			return Timings{}, fmt.Errorf("%s has chunks of %d rows", resume, m.ChunkRows)
+		}
+		m.verify()
+		manifest = resume
+	} else {
+		m = &exportManifest{Query: query, Output: output, Format: opts.Format, ChunkRows: chunkRows}
+		manifest = manifestPath(output)
+	}
+	opts.Format = m.Format
+
+	var timings Timings
+	for !m.Complete {
+		var firstRow int64
+		if n := len(m.Chunks); n > 0 {
+			firstRow = m.Chunks[n-1].FirstRow + m.Chunks[n-1].Rows
+		}
+		chunk := exportChunk{File: chunkFile(m.Output, len(m.Chunks)+1), FirstRow: firstRow}
+		f, err := os.Create(chunk.File)
+		if err != nil {
+			return timings, err
+		}
+		// all the chunks but the last hold ChunkRows rows
+		rows, t, err := printQuery(ctx, f, c, pagedQuery(query, m.ChunkRows, len(m.Chunks)), opts)
+		if err == nil {
+			err = f.Close()
+		} else {
+			f.Close()
+		}
+		if err != nil {
+			return timings, fmt.Errorf("chunk %s: %w", chunk.File, err)
+		}
+		timings.Add(t)
+
+		m.Complete = rows < int64(m.ChunkRows)
+		if rows == 0 && len(m.Chunks) > 0 {
+			// the previous chunk happened to end the results
+			os.Remove(chunk.File)
+		} else {
+			chunk.Rows = rows
+			if chunk.SHA256, err = fileSHA256(chunk.File); err != nil {
+				return timings, err
+			}
+			m.Chunks = append(m.Chunks, chunk)
+			fmt.Fprintf(os.Stderr, "Wrote %d rows (from row %d) to %s\n", rows, chunk.FirstRow, chunk.File)
+		}
+		if err := m.save(manifest); err != nil {
+			return timings, err
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Export complete: %d chunks, manifest in %s\n", len(m.Chunks), manifest)
+	return timings, nil
+}
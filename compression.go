@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/stats"
+)
+
+func init() {
+	// gzip registers itself when imported, zstd isn't bundled with grpc
+	encoding.RegisterCompressor(zstdCompressor{})
+}
+
+// zstdCompressor is the grpc compressor for the zstd message encoding.
+type zstdCompressor struct{}
+
+func (zstdCompressor) Name() string { return "zstd" }
+
+func (zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return zstd.NewWriter(w)
+}
+
+func (zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// compressionOptions returns the dial options compressing the messages with --compression,
+// and counting the bytes on the wire into wire.
+func compressionOptions(name string, wire *wireStats) []grpc.DialOption {
+	if name == "" || name == "none" {
+		return nil
+	}
+	if name == "gzip" {
+		name = gzip.Name
+	}
+	return []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.UseCompressor(name)),
+		grpc.WithStatsHandler(wire),
+	}
+}
+
+// wireStats counts the bytes received, as they travel on the wire (compressed) and once decompressed.
+type wireStats struct {
+	compressed   atomic.Int64
+	uncompressed atomic.Int64
+}
+
+func (s *wireStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (s *wireStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (s *wireStats) HandleConn(context.Context, stats.ConnStats)                       {}
+
+func (s *wireStats) HandleRPC(_ context.Context, st stats.RPCStats) {
+	if in, ok := st.(*stats.InPayload); ok {
+		s.compressed.Add(int64(in.CompressedLength))
+		s.uncompressed.Add(int64(in.Length))
+	}
+}
+
+func (s *wireStats) String() string {
+	compressed, uncompressed := s.compressed.Load(), s.uncompressed.Load()
+	ratio := 1.0
+	if compressed > 0 {
+		ratio = float64(uncompressed) / float64(compressed)
+	}
+	return fmt.Sprintf("Received: %s compressed, %s uncompressed (%.1fx)\n",
+		byteSize(compressed), byteSize(uncompressed), ratio)
+}
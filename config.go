@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the content of the flightclub configuration file.
+//
+// Each profile is a set of default flag values, keyed by flag name, e.g.:
+//
+//	profiles:
+//	  prod:
+//	    url: https://prod.example.com
+//	    db: telemetry
+//	    token: secret
+//	    headers:
+//	      iox-debug: "true"
+//
+// Any global flag can be set in a profile, so switching profiles reconfigures
+// identity (token, headers, auth flags) and routing (url, TLS flags) at once.
+type Config struct {
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	// Locations maps the locations of FlightInfo endpoints pointing to other servers (by URI or host:port)
+	// to the credentials needed to fetch them.
+	Locations map[string]LocationCredentials `yaml:"locations"`
+
+	// AuditLog is a file to which a JSON line is appended for every invocation, recording who ran
+	// what against which server, for how long, and with which outcome.
+	AuditLog string `yaml:"audit_log"`
+}
+
+// Profile maps flag names to their default values.
+type Profile = map[string]interface{}
+
+// defaultConfigPath returns $XDG_CONFIG_HOME/flightclub/config.yaml, or ~/.config/flightclub/config.yaml,
+// on every platform. If there's no such file, the one in the platform's configuration directory
+// (e.g. ~/Library/Application Support on macOS) is still read, as earlier versions did.
+func defaultConfigPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	path := filepath.Join(dir, "flightclub", "config.yaml")
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if platformDir, err := os.UserConfigDir(); err == nil {
+			if legacy := filepath.Join(platformDir, "flightclub", "config.yaml"); legacy != path {
+				if _, err := os.Stat(legacy); err == nil {
+					return legacy
+				}
+			}
+		}
+	}
+	return path
+}
+
+// loadConfig reads the configuration file at path.
+// A missing file is not an error and yields an empty configuration.
+func loadConfig(path string) (*Config, error) {
+	var cfg Config
+	if path == "" {
+		return &cfg, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &cfg, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// profileResolver returns a kong resolver that fills flags not given on the command line
+// (nor via their environment variables) from the profile selected with --profile.
+func profileResolver(cfg *Config) kong.Resolver {
+	return kong.ResolverFunc(func(ctx *kong.Context, parent *kong.Path, flag *kong.Flag) (interface{}, error) {
+		name := selectedProfile(ctx)
+		if name == "" || flag.Name == "profile" {
+			return nil, nil
+		}
+		profile := cfg.Profiles[name]
+		for _, env := range flag.Envs {
+			if _, ok := os.LookupEnv(env); ok {
+				return nil, nil
+			}
+		}
+		if v, ok := profile[flag.Name]; ok {
+			return v, nil
+		}
+		return profile[strings.ReplaceAll(flag.Name, "-", "_")], nil
+	})
+}
+
+// BeforeResolve checks that the profile selected with --profile exists.
+func (cli *CLI) BeforeResolve(ctx *kong.Context, cfg *Config) error {
+	if name := selectedProfile(ctx); name != "" {
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+	}
+	return nil
+}
+
+func selectedProfile(ctx *kong.Context) string {
+	for _, f := range ctx.Flags() {
+		if f.Name == "profile" {
+			s, _ := ctx.FlagValue(f).(string)
+			return s
+		}
+	}
+	return ""
+}
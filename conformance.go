@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ConformanceCmd exercises the Flight SQL surface of a server and reports a pass/fail matrix.
+type ConformanceCmd struct {
+	Query string `default:"SELECT 1" help:"Query used by the statement, prepared statement, transaction and cancellation checks"`
+}
+
+// errSkipped marks a check that could not run, e.g. because the server has no tables.
+var errSkipped = errors.New("skipped")
+
+type conformanceCheck struct {
+	name string
+	run  func(ctx context.Context, c *flightsql.Client) error
+}
+
+func (cmd *ConformanceCmd) Run(cli *Context) error {
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	var table *flightsql.TableRef
+
+	checks := []conformanceCheck{
+		{"GetCatalogs", func(ctx context.Context, c *flightsql.Client) error {
+			return checkInfo(c.GetCatalogs(ctx))(ctx, c)
+		}},
+		{"GetDbSchemas", func(ctx context.Context, c *flightsql.Client) error {
+			return checkInfo(c.GetDBSchemas(ctx, &flightsql.GetDBSchemasOpts{}))(ctx, c)
+		}},
+		{"GetTables", func(ctx context.Context, c *flightsql.Client) error {
+			info, err := c.GetTables(ctx, &flightsql.GetTablesOpts{IncludeSchema: true})
+			if err != nil {
+				return err
+			}
+			if err := checkInfo(info, nil)(ctx, c); err != nil {
+				return err
+			}
+			table, err = firstTable(ctx, c, info)
+			return err
+		}},
+		{"GetTableTypes", func(ctx context.Context, c *flightsql.Client) error {
+			return checkInfo(c.GetTableTypes(ctx))(ctx, c)
+		}},
+		{"GetSqlInfo", func(ctx context.Context, c *flightsql.Client) error {
+			return checkInfo(c.GetSqlInfo(ctx, nil))(ctx, c)
+		}},
+		{"GetXdbcTypeInfo", func(ctx context.Context, c *flightsql.Client) error {
+			return checkInfo(c.GetXdbcTypeInfo(ctx, nil))(ctx, c)
+		}},
+		{"GetPrimaryKeys", func(ctx context.Context, c *flightsql.Client) error {
+			if table == nil {
+				return errSkipped
+			}
+			return checkInfo(c.GetPrimaryKeys(ctx, *table))(ctx, c)
+		}},
+		{"GetImportedKeys", func(ctx context.Context, c *flightsql.Client) error {
+			if table == nil {
+				return errSkipped
+			}
+			return checkInfo(c.GetImportedKeys(ctx, *table))(ctx, c)
+		}},
+		{"GetExportedKeys", func(ctx context.Context, c *flightsql.Client) error {
+			if table == nil {
+				return errSkipped
+			}
+			return checkInfo(c.GetExportedKeys(ctx, *table))(ctx, c)
+		}},
+		{"GetCrossReference", func(ctx context.Context, c *flightsql.Client) error {
+			if table == nil {
+				return errSkipped
+			}
+			return checkInfo(c.GetCrossReference(ctx, *table, *table))(ctx, c)
+		}},
+		{"Statement", func(ctx context.Context, c *flightsql.Client) error {
+			return checkInfo(c.Execute(ctx, cmd.Query))(ctx, c)
+		}},
+		{"Statement schema", func(ctx context.Context, c *flightsql.Client) error {
+			res, err := c.GetExecuteSchema(ctx, cmd.Query)
+			if err != nil {
+				return err
+			}
+			schema, err := flight.DeserializeSchema(res.GetSchema(), memory.DefaultAllocator)
+			if err != nil {
+				return err
+			}
+			info, err := c.Execute(ctx, cmd.Query)
+			if err != nil {
+				return err
+			}
+			return checkStreamSchema(ctx, c, info, schema)
+		}},
+		{"Prepared statement", func(ctx context.Context, c *flightsql.Client) error {
+			prep, err := c.Prepare(ctx, cmd.Query)
+			if err != nil {
+				return err
+			}
+			if err := checkInfo(prep.Execute(ctx))(ctx, c); err != nil {
+				prep.Close(ctx)
+				return err
+			}
+			return prep.Close(ctx)
+		}},
+		{"Transaction commit", func(ctx context.Context, c *flightsql.Client) error {
+			tx, err := c.BeginTransaction(ctx)
+			if err != nil {
+				return err
+			}
+			if err := checkInfo(tx.Execute(ctx, cmd.Query))(ctx, c); err != nil {
+				tx.Rollback(ctx)
+				return err
+			}
+			return tx.Commit(ctx)
+		}},
+		{"Transaction rollback", func(ctx context.Context, c *flightsql.Client) error {
+			tx, err := c.BeginTransaction(ctx)
+			if err != nil {
+				return err
+			}
+			return tx.Rollback(ctx)
+		}},
+		{"Cancellation", func(ctx context.Context, c *flightsql.Client) error {
+			info, err := c.Execute(ctx, cmd.Query)
+			if err != nil {
+				return err
+			}
+			res, err := c.CancelFlightInfo(ctx, &flight.CancelFlightInfoRequest{Info: info})
+			if err != nil {
+				return err
+			}
+			if res.Status == flight.CancelStatusUnspecified {
+				return fmt.Errorf("unspecified cancel status")
+			}
+			return nil
+		}},
+	}
+
+	matrix := newStatsTable("Check", "Result", "Detail")
+	matrix.SetAlignment(tablewriter.ALIGN_LEFT)
+	failed := 0
+	for _, check := range checks {
+		result, detail := "PASS", ""
+		if err := check.run(ctx, c); errors.Is(err, errSkipped) {
+			result, detail = "SKIP", "no table found by GetTables"
+		} else if status.Code(err) == codes.Unimplemented {
+			result, detail = "UNSUPPORTED", status.Convert(err).Message()
+		} else if err != nil {
+			result, detail = "FAIL", err.Error()
+			failed++
+		}
+		matrix.Append([]string{check.name, result, detail})
+	}
+	matrix.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+	return nil
+}
+
+// checkInfo returns a check that fetches all the data of info and verifies it against
+// the schema advertised by the FlightInfo. It takes the results of a FlightInfo returning call directly.
+func checkInfo(info *flight.FlightInfo, err error) func(context.Context, *flightsql.Client) error {
+	return func(ctx context.Context, c *flightsql.Client) error {
+		if err != nil {
+			return err
+		}
+		schema, err := flight.DeserializeSchema(info.GetSchema(), memory.DefaultAllocator)
+		if err != nil {
+			return fmt.Errorf("FlightInfo schema: %w", err)
+		}
+		return checkStreamSchema(ctx, c, info, schema)
+	}
+}
+
+// checkStreamSchema verifies that the record batches returned by DoGet have the expected schema.
+func checkStreamSchema(ctx context.Context, c *flightsql.Client, info *flight.FlightInfo, expected *arrow.Schema) error {
+	_, err := forEachRecord(ctx, c, info, func(record arrow.Record) error {
+		if !record.Schema().Equal(expected) {
+			return fmt.Errorf("DoGet schema %s differs from FlightInfo schema %s", record.Schema(), expected)
+		}
+		return nil
+	})
+	return err
+}
+
+// firstTable returns a reference to the first table listed in a GetTables result, or nil if there are none.
+func firstTable(ctx context.Context, c *flightsql.Client, info *flight.FlightInfo) (*flightsql.TableRef, error) {
+	var ref *flightsql.TableRef
+	_, err := forEachRecord(ctx, c, info, func(record arrow.Record) error {
+		if ref != nil || record.NumRows() == 0 {
+			return nil
+		}
+		catalogs, _ := record.Column(0).(*array.String)
+		schemas, _ := record.Column(1).(*array.String)
+		tables, ok := record.Column(2).(*array.String)
+		if !ok || catalogs == nil || schemas == nil {
+			return fmt.Errorf("unexpected GetTables schema %s", record.Schema())
+		}
+
+		ref = &flightsql.TableRef{Table: tables.Value(0)}
+		if catalogs.IsValid(0) {
+			catalog := catalogs.Value(0)
+			ref.Catalog = &catalog
+		}
+		if schemas.IsValid(0) {
+			schema := schemas.Value(0)
+			ref.DBSchema = &schema
+		}
+		return nil
+	})
+	return ref, err
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// CopyCmd streams the results of a query on a server into a table of another one, without touching disk.
+type CopyCmd struct {
+	FromURL string `name:"from-url" placeholder:"URL" help:"Server to copy from, instead of --url"`
+	FromDB  string `name:"from-db" placeholder:"DB" help:"Database to copy from, instead of --db"`
+	Query   string `required:"" help:"Query selecting the rows to copy"`
+	ToURL   string `name:"to-url" placeholder:"URL" help:"Server to copy to (default: the one copied from, with the same credentials)"`
+	ToDB    string `name:"to-db" placeholder:"DB" help:"Database to copy to (default: the one copied from)"`
+	Table   string `required:"" help:"Table to insert the rows into"`
+	Create  bool   `help:"Create the table first, with the columns of the query results"`
+}
+
+// applySource makes --from-url and --from-db the server and database of cli, which they stand in for.
+func (cmd *CopyCmd) applySource(cli *CLI) {
+	if cmd.FromURL != "" {
+		cli.URL = cmd.FromURL
+	}
+	if cmd.FromDB != "" {
+		cli.DB = cmd.FromDB
+	}
+}
+
+func (cmd *CopyCmd) Run(cli *Context) error {
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// the rows are written as they're received, with --materialize
+	mctx, mc, err := cli.materializeClient(ctx, c, cmd.ToURL, cmd.ToDB)
+	if err != nil {
+		return err
+	}
+	if mc != c {
+		defer mc.Close()
+	}
+	m := newMaterializer(mctx, mc, cmd.Table, cmd.Create)
+	defer m.close()
+
+	start := time.Now()
+	info, err := c.Execute(ctx, cmd.Query)
+	if err != nil {
+		return err
+	}
+	m.announce(infoSchema(info))
+	if _, err := fetchRecords(ctx, c, info, executor(c, cmd.Query), m.add); err != nil {
+		return err
+	}
+	rows, err := m.finish()
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	fmt.Fprintf(os.Stderr, "Copied %d rows into %s in %s (%.0f rows/s)\n", rows, cmd.Table, elapsed, float64(rows)/elapsed.Seconds())
+	return nil
+}
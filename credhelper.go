@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// helperCredentials is what a credential helper prints on stdout, either as a JSON object:
+//
+//	{"token": "...", "headers": {"x-org-id": "42"}}
+//
+// or as a bare token.
+type helperCredentials struct {
+	Token   string            `json:"token"`
+	Headers map[string]string `json:"headers"`
+}
+
+// runCredentialHelper runs the external credential helper command, if configured,
+// and uses its output as token and additional headers.
+// Headers passed explicitly on the command line take precedence over the helper ones.
+func (cli *CLI) runCredentialHelper() error {
+	if cli.CredentialHelper == "" {
+		return nil
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", cli.CredentialHelper)
+	cmd.Env = append(os.Environ(), "FLIGHT_CLUB_URL="+cli.URL, "FLIGHT_CLUB_DB="+cli.DB)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("credential helper: %w", err)
+	}
+
+	var creds helperCredentials
+	out = bytes.TrimSpace(out)
+	if bytes.HasPrefix(out, []byte("{")) {
+		if err := json.Unmarshal(out, &creds); err != nil {
+			return fmt.Errorf("credential helper: parsing output: %w", err)
+		}
+	} else {
+		creds.Token = string(out)
+	}
+
+	if creds.Token != "" {
+		cli.Token = creds.Token
+	}
+	for k, v := range creds.Headers {
+		if cli.Headers == nil {
+			cli.Headers = map[string]string{}
+		}
+		if _, ok := cli.Headers[k]; !ok {
+			cli.Headers[k] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// csvWriter streams results as CSV (or TSV), one line per row. NULL values are written as empty fields,
+// unless --null-string is given.
+type csvWriter struct {
+	w      csvLineWriter
+	opts   printOptions
+	header bool
+}
+
+// csvLineWriter writes the fields of a line of CSV, like a csv.Writer.
+type csvLineWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+func newCSVWriter(w io.Writer, opts printOptions, comma rune) *csvWriter {
+	if opts.CSVDelimiter != 0 {
+		comma = opts.CSVDelimiter
+	}
+	var lw csvLineWriter
+	if opts.CSVQuote == 0 || opts.CSVQuote == '"' {
+		cw := csv.NewWriter(w)
+		cw.Comma = comma
+		lw = cw
+	} else {
+		lw = &quotingWriter{w: bufio.NewWriter(w), comma: comma, quote: opts.CSVQuote}
+	}
+	return &csvWriter{w: lw, opts: opts, header: !opts.NoHeader}
+}
+
+func (c *csvWriter) WriteRecord(record arrow.Record) error {
+	if c.header {
+		if err := c.w.Write(getHeader(record)); err != nil {
+			return err
+		}
+		c.header = false
+	}
+
+	schema := record.Schema()
+	line := make([]string, record.NumCols())
+	for r := 0; r < int(record.NumRows()); r++ {
+		for col := range line {
+			column := record.Column(col)
+			if column.IsNull(r) {
+				line[col] = c.opts.nullString("")
+				continue
+			}
+			s, err := c.opts.renderColumn(schema.Field(col), column, r)
+			if err != nil {
+				return err
+			}
+			if c.opts.DecimalComma && isFractional(column.DataType()) {
+				s = strings.Replace(s, ".", ",", 1)
+			}
+			line[col] = s
+		}
+		if err := c.w.Write(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// parseCSVChar parses the character given to a CSV dialect flag: a single character, or \t for a tab.
+func parseCSVChar(flag, s string) (rune, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if s == `\t` {
+		return '\t', nil
+	}
+	r := []rune(s)
+	if len(r) != 1 || r[0] == '\n' || r[0] == '\r' {
+		return 0, fmt.Errorf("%s must be a single character, got %q", flag, s)
+	}
+	return r[0], nil
+}
+
+// isFractional tells whether values of type dt may have a decimal separator.
+func isFractional(dt arrow.DataType) bool {
+	return arrow.IsFloating(dt.ID()) || arrow.IsDecimal(dt.ID())
+}
+
+// quotingWriter writes CSV like a csv.Writer, but quoting fields with a character other than '"'.
+type quotingWriter struct {
+	w     *bufio.Writer
+	comma rune
+	quote rune
+	err   error
+}
+
+func (q *quotingWriter) Write(record []string) error {
+	for i, field := range record {
+		if i > 0 {
+			q.w.WriteRune(q.comma)
+		}
+		if !q.needsQuotes(field) {
+			q.w.WriteString(field)
+			continue
+		}
+		quote := string(q.quote)
+		q.w.WriteString(quote)
+		q.w.WriteString(strings.ReplaceAll(field, quote, quote+quote))
+		q.w.WriteString(quote)
+	}
+	_, err := q.w.WriteString("\n")
+	if err != nil && q.err == nil {
+		q.err = err
+	}
+	return err
+}
+
+func (q *quotingWriter) needsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	return strings.ContainsRune(field, q.comma) || strings.ContainsRune(field, q.quote) ||
+		strings.ContainsAny(field, "\r\n") || field[0] == ' ' || field[0] == '\t'
+}
+
+func (q *quotingWriter) Flush() {
+	if err := q.w.Flush(); err != nil && q.err == nil {
+		q.err = err
+	}
+}
+
+func (q *quotingWriter) Error() error {
+	return q.err
+}
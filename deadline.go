@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// phaseTracker records which phase of the invocation (connect, warmup, execute, fetch, render) is running,
+// so that hitting the --deadline can be reported with what was going on and what had been done so far.
+// A nil phaseTracker records nothing.
+type phaseTracker struct {
+	mu      sync.Mutex
+	current string
+	started time.Time
+	done    []phaseTime
+	rows    int64
+}
+
+type phaseTime struct {
+	name     string
+	duration time.Duration
+}
+
+// enter ends the current phase, if any, and starts the named one.
+func (p *phaseTracker) enter(name string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if p.current != "" {
+		p.done = append(p.done, phaseTime{p.current, now.Sub(p.started)})
+	}
+	p.current, p.started = name, now
+}
+
+func (p *phaseTracker) addRows(n int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.rows += n
+}
+
+// report describes the active phase and the statistics gathered before the deadline was hit.
+func (p *phaseTracker) report(w io.Writer, deadline time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	current := p.current
+	if current == "" {
+		current = "setup"
+	}
+	fmt.Fprintf(w, "Deadline of %s exceeded during the %s phase\n", deadline, current)
+	var phases []string
+	for _, ph := range p.done {
+		phases = append(phases, fmt.Sprintf("%s: %s", ph.name, ph.duration))
+	}
+	if p.current != "" {
+		phases = append(phases, fmt.Sprintf("%s: %s (interrupted)", p.current, time.Since(p.started)))
+	}
+	if len(phases) > 0 {
+		fmt.Fprintln(w, strings.Join(phases, ", "))
+	}
+	fmt.Fprintf(w, "%d rows received before the deadline\n", p.rows)
+}
+
+type phaseKey struct{}
+
+func withPhases(ctx context.Context, p *phaseTracker) context.Context {
+	return context.WithValue(ctx, phaseKey{}, p)
+}
+
+// phasesFrom returns the phase tracker of ctx, or nil if there is no --deadline.
+func phasesFrom(ctx context.Context) *phaseTracker {
+	p, _ := ctx.Value(phaseKey{}).(*phaseTracker)
+	return p
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// grpcDebugLog logs every gRPC call (--debug-grpc): its method and outgoing metadata when it starts, then its
+// status, latency and the messages exchanged when it ends. Being a stats handler rather than an interceptor,
+// it sees the metadata as sent on the wire, after the auth middleware has set the credentials.
+type grpcDebugLog struct {
+	w     io.Writer
+	mu    sync.Mutex
+	calls atomic.Int64
+}
+
+// debugCall accumulates the messages of a call.
+type debugCall struct {
+	id           int64
+	method       string
+	sent, recv   int
+	sentB, recvB int
+}
+
+type debugCallKey struct{}
+
+func newGRPCDebugLog(w io.Writer) *grpcDebugLog {
+	return &grpcDebugLog{w: w}
+}
+
+func (l *grpcDebugLog) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	call := &debugCall{id: l.calls.Add(1), method: info.FullMethodName}
+	return context.WithValue(ctx, debugCallKey{}, call)
+}
+
+func (l *grpcDebugLog) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (l *grpcDebugLog) HandleConn(context.Context, stats.ConnStats)                       {}
+
+func (l *grpcDebugLog) HandleRPC(ctx context.Context, st stats.RPCStats) {
+	call, ok := ctx.Value(debugCallKey{}).(*debugCall)
+	if !ok || !st.IsClient() {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	switch st := st.(type) {
+	case *stats.OutHeader:
+		fmt.Fprintf(l.w, "grpc #%d → %s\n", call.id, call.method)
+		for _, line := range debugMetadata(st.Header) {
+			fmt.Fprintf(l.w, "grpc #%d   %s\n", call.id, line)
+		}
+	case *stats.OutPayload:
+		call.sent, call.sentB = call.sent+1, call.sentB+st.Length
+	case *stats.InPayload:
+		call.recv, call.recvB = call.recv+1, call.recvB+st.Length
+	case *stats.End:
+		s, _ := status.FromError(st.Error)
+		line := fmt.Sprintf("grpc #%d ← %s %s in %s, sent %d messages (%s), received %d messages (%s)",
+			call.id, path.Base(call.method), s.Code(), st.EndTime.Sub(st.BeginTime),
+			call.sent, byteSize(call.sentB), call.recv, byteSize(call.recvB))
+		if st.Error != nil {
+			line += ": " + s.Message()
+		}
+		fmt.Fprintln(l.w, line)
+	}
+}
+
+// debugMetadata returns the metadata as sorted "key: value" lines, hiding the credentials
+// but keeping the authorization scheme, which is often what's wrong.
+func debugMetadata(md metadata.MD) []string {
+	var lines []string
+	for k, values := range md {
+		for _, v := range values {
+			switch {
+			case k == "authorization":
+				scheme, _, _ := strings.Cut(v, " ")
+				v = scheme + " [redacted]"
+			case isSecretHeader(k):
+				v = "[redacted]"
+			case strings.HasSuffix(k, "-bin"):
+				v = fmt.Sprintf("(%d bytes)", len(v))
+			}
+			lines = append(lines, k+": "+v)
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func isSecretHeader(key string) bool {
+	for _, s := range []string{"token", "secret", "password", "cookie", "key", "auth"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
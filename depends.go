@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// readOnlyKeywords start the statements that only read data, which may run concurrently with each other.
+var readOnlyKeywords = map[string]bool{
+	"SELECT": true, "WITH": true, "SHOW": true, "EXPLAIN": true, "DESCRIBE": true, "VALUES": true, "TABLE": true,
+}
+
+// statementDeps returns, for each statement, the earlier statements it must wait for when statements run
+// concurrently (--jobs).
+//
+// A statement may declare them with the name and after directives, naming statements by their name directive
+// or their position (starting from 1):
+//
+//	-- flightclub: name=setup
+//	CREATE TABLE t AS SELECT ...;
+//	-- flightclub: after=setup
+//	SELECT count(*) FROM t;
+//
+// Otherwise they're inferred from the kind of statement: a statement writing data (CREATE, INSERT, ...) waits
+// for all the statements before it, and a read-only one (SELECT, ...) waits for the writes before it.
+func statementDeps(statements []string) ([][]int, error) {
+	names := map[string]int{}
+	deps := make([][]int, len(statements))
+	lastWrite := -1
+	for i, stmt := range statements {
+		d, err := parseDirectives(stmt)
+		if err != nil {
+			return nil, fmt.Errorf("statement %d: %w", i+1, err)
+		}
+		if d.Name != "" {
+			if _, err := strconv.Atoi(d.Name); err == nil {
+				return nil, fmt.Errorf("statement %d: the name %q would be taken for a position", i+1, d.Name)
+			}
+			if _, ok := names[d.Name]; ok {
+				return nil, fmt.Errorf("statement %d: duplicate name %q", i+1, d.Name)
+			}
+			names[d.Name] = i
+		}
+
+		write := !readOnlyKeywords[leadingKeyword(stmt)]
+		switch {
+		case d.After != nil:
+			for _, name := range d.After {
+				dep, ok := names[name]
+				if n, err := strconv.Atoi(name); err == nil {
+					dep, ok = n-1, n >= 1 && n <= len(statements)
+				}
+				if !ok || dep >= i {
+					return nil, fmt.Errorf("statement %d: after=%s must name an earlier statement", i+1, name)
+				}
+				deps[i] = append(deps[i], dep)
+			}
+		case write:
+			for dep := 0; dep < i; dep++ {
+				deps[i] = append(deps[i], dep)
+			}
+		case lastWrite >= 0:
+			// the writes before it are ordered after all their predecessors already
+			deps[i] = []int{lastWrite}
+		}
+		if write {
+			lastWrite = i
+		}
+	}
+	return deps, nil
+}
+
+// leadingKeyword returns the first word of the statement, in upper case, skipping the comments before it.
+func leadingKeyword(stmt string) string {
+	for i := 0; i < len(stmt); i++ {
+		switch {
+		case strings.HasPrefix(stmt[i:], "--"):
+			i = skipUntil(stmt, i+2, "\n")
+		case strings.HasPrefix(stmt[i:], "/*"):
+			i = skipBlockComment(stmt, i)
+		case stmt[i] == '(':
+			// a parenthesized query
+		case !unicode.IsSpace(rune(stmt[i])):
+			end := strings.IndexFunc(stmt[i:], func(r rune) bool { return !unicode.IsLetter(r) })
+			if end < 0 {
+				end = len(stmt) - i
+			}
+			return strings.ToUpper(stmt[i : i+end])
+		}
+	}
+	return ""
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const directivePrefix = "-- flightclub:"
+
+// directives control how a single statement of a SQL file is run. They are written as comments
+// preceding the statement:
+//
+//	-- flightclub: timeout=60s output=foo.txt
+//	SELECT * FROM foo;
+type directives struct {
+	Timeout time.Duration
+	Format  string
+	Output  string
+
+	// Name and After order the ;-separated statements run concurrently (see statementDeps).
+	Name  string
+	After []string
+}
+
+// parseDirectives extracts the directives embedded in the comments of a statement.
+func parseDirectives(stmt string) (directives, error) {
+	var d directives
+	scanner := bufio.NewScanner(strings.NewReader(stmt))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, directivePrefix) {
+			continue
+		}
+		for _, kv := range strings.Fields(strings.TrimPrefix(line, directivePrefix)) {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				return d, fmt.Errorf("invalid directive %q, expecting key=value", kv)
+			}
+			switch k {
+			case "timeout":
+				timeout, err := time.ParseDuration(v)
+				if err != nil {
+					return d, fmt.Errorf("invalid timeout directive: %w", err)
+				}
+				d.Timeout = timeout
+			case "format":
+				switch v {
+				case "table", "json", "ndjson", "csv", "tsv", "parquet", "arrow", "xlsx":
+				default:
+					return d, fmt.Errorf("unsupported format %q", v)
+				}
+				d.Format = v
+			case "output":
+				d.Output = v
+			case "name":
+				d.Name = v
+			case "after":
+				d.After = strings.Split(v, ",")
+			default:
+				return d, fmt.Errorf("unknown directive %q", k)
+			}
+		}
+	}
+	return d, scanner.Err()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"crypto/sha256"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// rowKey identifies a row by a hash of its rendered values, which is much smaller than the row
+// and, at 128 bits, practically free of collisions.
+type rowKey [16]byte
+
+// deduplicator drops the rows already seen (--distinct). With a limit, it only remembers the last limit
+// distinct rows, so that memory stays bounded on huge results, at the cost of letting through duplicates
+// that are farther apart.
+type deduplicator struct {
+	limit int
+
+	seen map[rowKey]struct{}
+	// order of the remembered keys, oldest first, when limited
+	order   []rowKey
+	next    int
+	removed int64
+}
+
+func newDeduplicator(limit int) *deduplicator {
+	return &deduplicator{limit: limit, seen: map[rowKey]struct{}{}}
+}
+
+// filter returns a record with the rows of record not seen before.
+func (d *deduplicator) filter(record arrow.Record, opts renderOptions) (arrow.Record, error) {
+	schema := record.Schema()
+	return selectRows(record, func(r int) (bool, error) {
+		h := sha256.New()
+		for c, col := range record.Columns() {
+			// NULLs are told apart from any rendered value, such as 'NULL'
+			if col.IsNull(r) {
+				h.Write([]byte{0})
+				continue
+			}
+			s, err := opts.renderColumn(schema.Field(c), col, r)
+			if err != nil {
+				return false, err
+			}
+			h.Write([]byte{1})
+			h.Write([]byte(s))
+			h.Write([]byte{0x1f})
+		}
+		var key rowKey
+		copy(key[:], h.Sum(nil))
+		if _, ok := d.seen[key]; ok {
+			d.removed++
+			return false, nil
+		}
+		d.remember(key)
+		return true, nil
+	})
+}
+
+// removedRows returns the number of duplicate rows dropped so far.
+func (d *deduplicator) removedRows() int64 {
+	if d == nil {
+		return 0
+	}
+	return d.removed
+}
+
+func (d *deduplicator) remember(key rowKey) {
+	d.seen[key] = struct{}{}
+	if d.limit <= 0 {
+		return
+	}
+	if len(d.order) < d.limit {
+		d.order = append(d.order, key)
+		return
+	}
+	// forget the oldest key, in its slot of the ring
+	delete(d.seen, d.order[d.next])
+	d.order[d.next] = key
+	d.next = (d.next + 1) % d.limit
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DoctorCmd diagnoses connection problems by walking through each step of connecting to the server.
+type DoctorCmd struct {
+	Query   string        `default:"SELECT 1" help:"Trivial query run as the last step"`
+	Timeout time.Duration `default:"10s" help:"Timeout of each step"`
+}
+
+func (cmd *DoctorCmd) Run(cli *Context) error {
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+
+	u, err := url.Parse(cli.URL)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := cli.tlsConfig()
+	if err != nil {
+		return err
+	}
+	addr, cred, err := parseAddr(cli.URL, tlsConfig)
+	if err != nil {
+		return err
+	}
+	host, port, _ := net.SplitHostPort(addr)
+	socket, isSocket := strings.CutPrefix(addr, "unix:")
+	connectStep := "TCP connect"
+	if isSocket {
+		connectStep = "Socket connect"
+	}
+
+	var (
+		conn *grpc.ClientConn
+		c    *flightsql.Client
+	)
+	steps := []struct {
+		name string
+		run  func(ctx context.Context) (string, error)
+	}{
+		{"DNS resolution", func(ctx context.Context) (string, error) {
+			if isSocket {
+				return "skipped, unix socket", nil
+			}
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			return strings.Join(addrs, ", "), err
+		}},
+		{connectStep, func(ctx context.Context) (string, error) {
+			var d net.Dialer
+			if isSocket {
+				conn, err := d.DialContext(ctx, "unix", socket)
+				if err != nil {
+					return "", err
+				}
+				conn.Close()
+				return socket, nil
+			}
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return "", err
+			}
+			defer conn.Close()
+			return fmt.Sprintf("%s -> %s", conn.LocalAddr(), conn.RemoteAddr()), nil
+		}},
+		{"TLS handshake", func(ctx context.Context) (string, error) {
+			if u.Scheme != "https" {
+				return "skipped, plaintext connection", nil
+			}
+			config := tlsConfig.Clone()
+			if config.ServerName == "" {
+				config.ServerName = host
+			}
+			d := tls.Dialer{Config: config}
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return "", err
+			}
+			defer conn.Close()
+			return describeTLS(conn.(*tls.Conn).ConnectionState()), nil
+		}},
+		{"gRPC channel", func(ctx context.Context) (string, error) {
+			var err error
+			mw := flight.CreateClientMiddleware(cli)
+			conn, err = grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(cred), grpc.WithBlock(),
+				grpc.WithChainUnaryInterceptor(mw.Unary), grpc.WithChainStreamInterceptor(mw.Stream))
+			if err != nil {
+				return "", err
+			}
+			c = &flightsql.Client{Client: flight.NewClientFromConn(conn, cli)}
+			return conn.GetState().String(), nil
+		}},
+		{"Authentication", func(ctx context.Context) (string, error) {
+			if cli.Username != "" {
+				if err := cli.handshake(ctx, c); err != nil {
+					return "", fmt.Errorf("%w (check --username and --password)", err)
+				}
+			}
+			_, err := c.GetCatalogs(ctx)
+			switch status.Code(err) {
+			case codes.Unimplemented:
+				// the server got past authentication to tell us it doesn't implement GetCatalogs
+				return "ok (GetCatalogs not implemented)", nil
+			case codes.Unauthenticated, codes.PermissionDenied:
+				return "", fmt.Errorf("%w (check --token and --db)", err)
+			}
+			return "ok", err
+		}},
+		{"Query", func(ctx context.Context) (string, error) {
+			rows, t, err := runQuery(ctx, c, cmd.Query)
+			return fmt.Sprintf("%d rows, %s", rows, strings.TrimSpace(t.String())), err
+		}},
+	}
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	if isSocket {
+		fmt.Printf("Diagnosing %s (%s)\n\n", cli.URL, socket)
+	} else {
+		fmt.Printf("Diagnosing %s (%s:%s)\n\n", cli.URL, host, port)
+	}
+	for _, step := range steps {
+		stepCtx, cancel := context.WithTimeout(ctx, cmd.Timeout)
+		start := time.Now()
+		detail, err := step.run(stepCtx)
+		elapsed := time.Since(start).Round(time.Microsecond)
+		cancel()
+
+		if err != nil {
+			fmt.Printf("FAIL  %-16s %s\n      %v\n", step.name, elapsed, err)
+			return fmt.Errorf("%s failed", strings.ToLower(step.name))
+		}
+		fmt.Printf("ok    %-16s %s  %s\n", step.name, elapsed, indent(detail, 30))
+	}
+	fmt.Fprintln(os.Stdout, "\nAll steps succeeded.")
+	return nil
+}
+
+// describeTLS summarizes the negotiated TLS parameters and the peer certificate chain.
+func describeTLS(state tls.ConnectionState) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	for i, cert := range state.PeerCertificates {
+		fmt.Fprintf(&b, "\n%d: subject=%s\n   issuer=%s\n   valid %s to %s",
+			i, cert.Subject, cert.Issuer, cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly))
+	}
+	return b.String()
+}
+
+func indent(s string, n int) string {
+	return strings.ReplaceAll(s, "\n", "\n"+strings.Repeat(" ", n))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// applyDSN fills the connection flags from a --dsn connection string, like those of ADBC tooling:
+//
+//	flightsql://token@host:443/dbname?tls=true&header.iox-debug=true
+//
+// The scheme may also be grpc, grpc+tcp (plaintext), grpc+tls, or grpc+unix for a Unix domain socket, whose path
+// is then the socket's (and the database is given as a parameter). The user info is a token, or a username
+// and password for the basic auth handshake. TLS defaults to on for grpc+tls and for port 443, and the
+// parameters are:
+//
+//	tls=BOOL                   connect with TLS
+//	token=TOKEN                same as --token
+//	username=USER, password=P  same as --username and --password
+//	database=DB                same as --db, instead of the path
+//	header.NAME=VALUE          same as -H NAME=VALUE
+//
+// Flags given explicitly take precedence over the DSN.
+func (cli *CLI) applyDSN() error {
+	if cli.DSN == "" {
+		return nil
+	}
+	u, err := url.Parse(cli.DSN)
+	if err != nil {
+		return fmt.Errorf("invalid --dsn: %w", err)
+	}
+	useTLS := u.Port() == "443"
+	switch u.Scheme {
+	case "flightsql", "grpc", "grpc+tcp":
+	case "grpc+tls":
+		useTLS = true
+	case "grpc+unix":
+	default:
+		return fmt.Errorf("invalid --dsn: unsupported scheme %q (expecting flightsql, grpc, grpc+tcp, grpc+tls or grpc+unix)", u.Scheme)
+	}
+	if u.Hostname() == "" && u.Scheme != "grpc+unix" {
+		return fmt.Errorf("invalid --dsn: missing host")
+	}
+
+	var token, username, password string
+	if u.User != nil {
+		if p, ok := u.User.Password(); ok {
+			username, password = u.User.Username(), p
+		} else {
+			token = u.User.Username()
+		}
+	}
+	db := strings.Trim(u.Path, "/")
+	if u.Scheme == "grpc+unix" {
+		// the path is the socket's
+		db = ""
+	}
+	for k, values := range u.Query() {
+		v := values[len(values)-1]
+		switch {
+		case k == "tls":
+			if useTLS, err = strconv.ParseBool(v); err != nil {
+				return fmt.Errorf("invalid --dsn parameter tls=%s", v)
+			}
+		case k == "token":
+			token = v
+		case k == "username":
+			username = v
+		case k == "password":
+			password = v
+		case k == "database":
+			db = v
+		case strings.HasPrefix(k, "header."):
+			name := strings.TrimPrefix(k, "header.")
+			if _, ok := cli.Headers[name]; !ok {
+				if cli.Headers == nil {
+					cli.Headers = map[string]string{}
+				}
+				cli.Headers[name] = v
+			}
+		default:
+			return fmt.Errorf("invalid --dsn: unknown parameter %q", k)
+		}
+	}
+
+	scheme := "http"
+	if useTLS {
+		scheme = "https"
+	}
+	cli.URL = (&url.URL{Scheme: scheme, Host: u.Host}).String()
+	if u.Scheme == "grpc+unix" {
+		cli.URL = (&url.URL{Scheme: "unix", Path: u.Path}).String()
+	}
+	cli.DB = db
+	if cli.Token == "" {
+		cli.Token = token
+	}
+	if cli.Username == "" {
+		cli.Username = username
+	}
+	if cli.Password == "" {
+		cli.Password = password
+	}
+	return nil
+}
+
+// checkTarget reports a missing --url or --db, when not set by --dsn either.
+func (cli *CLI) checkTarget() error {
+	var missing []string
+	if cli.URL == "" {
+		missing = append(missing, "--url=STRING")
+	}
+	if cli.DB == "" {
+		missing = append(missing, "--db=STRING")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing flags: %s (or --dsn=DSN)", strings.Join(missing, ", "))
+	}
+	return nil
+}
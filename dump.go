@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+)
+
+// flightDataDump writes the raw FlightData messages of every DoGet stream to a directory, byte for byte
+// as received, before they are decoded:
+//
+//	0001.ticket                 the ticket of the first DoGet
+//	0001-000000.header          the IPC message header (flatbuffers) of its first message
+//	0001-000000.body            the IPC message body
+//	0001-000000.app_metadata    the application metadata, if any
+//
+// A nil flightDataDump dumps nothing.
+type flightDataDump struct {
+	dir string
+
+	mu       sync.Mutex
+	streams  int
+	messages map[int]int
+}
+
+func newFlightDataDump(dir string) (*flightDataDump, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &flightDataDump{dir: dir, messages: map[int]int{}}, nil
+}
+
+// newStream allocates the number of a new DoGet stream, saving its ticket.
+func (d *flightDataDump) newStream(ticket *flight.Ticket) int {
+	if d == nil {
+		return 0
+	}
+	d.mu.Lock()
+	d.streams++
+	id := d.streams
+	d.mu.Unlock()
+	if err := os.WriteFile(filepath.Join(d.dir, fmt.Sprintf("%04d.ticket", id)), ticket.GetTicket(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Dumping ticket: %v\n", err)
+	}
+	return id
+}
+
+// write saves the next message of stream id.
+func (d *flightDataDump) write(id int, data *flight.FlightData) error {
+	if d == nil {
+		return nil
+	}
+	d.mu.Lock()
+	seq := d.messages[id]
+	d.messages[id]++
+	d.mu.Unlock()
+
+	prefix := filepath.Join(d.dir, fmt.Sprintf("%04d-%06d", id, seq))
+	parts := []struct {
+		ext  string
+		data []byte
+	}{
+		{".header", data.DataHeader},
+		{".body", data.DataBody},
+		{".app_metadata", data.AppMetadata},
+	}
+	for _, p := range parts {
+		if p.ext == ".app_metadata" && len(p.data) == 0 {
+			continue
+		}
+		if err := os.WriteFile(prefix+p.ext, p.data, 0o644); err != nil {
+			return fmt.Errorf("dumping flight data: %w", err)
+		}
+	}
+	return nil
+}
+
+type flightDataDumpKey struct{}
+
+func withFlightDataDump(ctx context.Context, d *flightDataDump) context.Context {
+	return context.WithValue(ctx, flightDataDumpKey{}, d)
+}
+
+// flightDataDumpFrom returns the flight data dump of ctx, or nil if messages aren't dumped.
+func flightDataDumpFrom(ctx context.Context) *flightDataDump {
+	d, _ := ctx.Value(flightDataDumpKey{}).(*flightDataDump)
+	return d
+}
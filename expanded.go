@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// expandedWriter streams results vertically, one "column | value" line per column, like psql's \x:
+//
+//	-[ RECORD 1 ]---------
+//	time      | 2024-01-01 00:00:00
+//	host      | server01
+//	usage_cpu | 12.5
+type expandedWriter struct {
+	w    *bufio.Writer
+	opts printOptions
+
+	rows int64
+}
+
+func newExpandedWriter(w io.Writer, opts printOptions) *expandedWriter {
+	return &expandedWriter{w: bufio.NewWriter(w), opts: opts}
+}
+
+func (e *expandedWriter) WriteRecord(record arrow.Record) error {
+	header := getHeader(record)
+	if e.opts.ShowTypes {
+		for i, t := range getTypes(record) {
+			header[i] += " (" + t + ")"
+		}
+	}
+	width := 0
+	for _, h := range header {
+		if n := utf8.RuneCountInString(h); n > width {
+			width = n
+		}
+	}
+
+	rows, err := e.opts.renderRecord(record)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		e.rows++
+		fmt.Fprintf(e.w, "-[ RECORD %d ]%s\n", e.rows, strings.Repeat("-", width))
+		for c, value := range row {
+			// continuation lines of multi-line values are aligned with the first one
+			value = strings.ReplaceAll(value, "\n", "\n"+strings.Repeat(" ", width)+" | ")
+			fmt.Fprintf(e.w, "%-*s | %s\n", width, header[c], value)
+		}
+	}
+	return nil
+}
+
+func (e *expandedWriter) Close() error {
+	if e.rows == 0 {
+		fmt.Fprintln(e.w, "(0 rows)")
+	}
+	return e.w.Flush()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// benchReport is the full result set of a bench run, as written by --export.
+type benchReport struct {
+	Query   string       `json:"query"`
+	URL     string       `json:"url"`
+	DB      string       `json:"db"`
+	Started time.Time    `json:"started"`
+	Runs    []runReport  `json:"runs,omitempty"`
+	Steps   []stepReport `json:"steps,omitempty"`
+}
+
+// runReport is a single iteration of a bench series.
+type runReport struct {
+	Kind    string  `json:"kind"`
+	Rows    int64   `json:"rows"`
+	Execute float64 `json:"execute_seconds"`
+	DoGet   float64 `json:"doget_seconds"`
+	Total   float64 `json:"total_seconds"`
+}
+
+// stepReport is a step of constant concurrency of a load ramp.
+type stepReport struct {
+	Concurrency int       `json:"concurrency"`
+	Elapsed     float64   `json:"elapsed_seconds"`
+	Latencies   []float64 `json:"latencies_seconds"`
+	Errors      []string  `json:"errors,omitempty"`
+}
+
+func (r *benchReport) addRuns(kind string, s benchSamples) {
+	for i := range s.Total {
+		r.Runs = append(r.Runs, runReport{
+			Kind:    kind,
+			Rows:    s.Rows[i],
+			Execute: s.Execute[i].Seconds(),
+			DoGet:   s.DoGet[i].Seconds(),
+			Total:   s.Total[i].Seconds(),
+		})
+	}
+}
+
+func (r *benchReport) addSteps(results []loadResult) {
+	for _, res := range results {
+		step := stepReport{
+			Concurrency: res.Concurrency,
+			Elapsed:     res.Elapsed.Seconds(),
+			Errors:      res.Errors,
+		}
+		for _, l := range res.Latencies {
+			step.Latencies = append(step.Latencies, l.Seconds())
+		}
+		r.Steps = append(r.Steps, step)
+	}
+}
+
+// export writes the report to filename, in JSON or OpenMetrics text format.
+func (r *benchReport) export(filename, format string) error {
+	if format == "auto" {
+		format = "openmetrics"
+		if strings.EqualFold(filepath.Ext(filename), ".json") {
+			format = "json"
+		}
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(r)
+	case "openmetrics":
+		err = r.writeOpenMetrics(w)
+	default:
+		err = fmt.Errorf("unsupported export format %q", format)
+	}
+	if err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+func (r *benchReport) writeOpenMetrics(w io.Writer) error {
+	if len(r.Runs) > 0 {
+		fmt.Fprintln(w, "# TYPE flightclub_bench_run_duration_seconds gauge")
+		fmt.Fprintln(w, "# UNIT flightclub_bench_run_duration_seconds seconds")
+		for i, run := range r.Runs {
+			for _, p := range []struct {
+				phase string
+				value float64
+			}{{"execute", run.Execute}, {"doget", run.DoGet}, {"total", run.Total}} {
+				fmt.Fprintf(w, "flightclub_bench_run_duration_seconds{kind=%q,run=\"%d\",phase=%q} %g\n", run.Kind, i, p.phase, p.value)
+			}
+		}
+		fmt.Fprintln(w, "# TYPE flightclub_bench_run_rows gauge")
+		for i, run := range r.Runs {
+			fmt.Fprintf(w, "flightclub_bench_run_rows{kind=%q,run=\"%d\"} %d\n", run.Kind, i, run.Rows)
+		}
+	}
+
+	if len(r.Steps) > 0 {
+		fmt.Fprintln(w, "# TYPE flightclub_load_latency_seconds summary")
+		fmt.Fprintln(w, "# UNIT flightclub_load_latency_seconds seconds")
+		for _, step := range r.Steps {
+			var latencies []time.Duration
+			var sum float64
+			for _, l := range step.Latencies {
+				latencies = append(latencies, time.Duration(l*float64(time.Second)))
+				sum += l
+			}
+			st := newDurationStats(latencies)
+			for _, q := range []struct {
+				quantile string
+				value    time.Duration
+			}{{"0.5", st.P50}, {"0.95", st.P95}, {"0.99", st.P99}} {
+				fmt.Fprintf(w, "flightclub_load_latency_seconds{concurrency=\"%d\",quantile=%q} %g\n", step.Concurrency, q.quantile, q.value.Seconds())
+			}
+			fmt.Fprintf(w, "flightclub_load_latency_seconds_sum{concurrency=\"%d\"} %g\n", step.Concurrency, sum)
+			fmt.Fprintf(w, "flightclub_load_latency_seconds_count{concurrency=\"%d\"} %d\n", step.Concurrency, len(step.Latencies))
+		}
+		fmt.Fprintln(w, "# TYPE flightclub_load_errors counter")
+		for _, step := range r.Steps {
+			fmt.Fprintf(w, "flightclub_load_errors_total{concurrency=\"%d\"} %d\n", step.Concurrency, len(step.Errors))
+		}
+	}
+
+	_, err := fmt.Fprintln(w, "# EOF")
+	return err
+}
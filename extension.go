@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+)
+
+// extensionRenderer renders a value of an Arrow extension type given its storage array.
+type extensionRenderer func(o renderOptions, storage arrow.Array, row int) (string, error)
+
+// extensionRenderers maps Arrow extension type names to their renderers.
+// Extension types without a renderer are printed as their storage value wrapped in the extension name.
+var extensionRenderers = map[string]extensionRenderer{}
+
+func init() {
+	registerExtensionRenderer("arrow.uuid", renderStorage)
+	registerExtensionRenderer("arrow.json", renderStorage)
+}
+
+// registerExtensionRenderer installs a custom renderer for the extension type named name.
+func registerExtensionRenderer(name string, r extensionRenderer) {
+	extensionRenderers[name] = r
+}
+
+func renderStorage(o renderOptions, storage arrow.Array, row int) (string, error) {
+	return o.renderText(storage, row)
+}
+
+func (o renderOptions) renderExtension(name string, storage arrow.Array, row int) (string, error) {
+	if storage.IsNull(row) {
+		return o.nullString("NULL"), nil
+	}
+	if r, ok := extensionRenderers[name]; ok {
+		return r(o, storage, row)
+	}
+	s, err := o.renderText(storage, row)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s(%s)", name, s), nil
+}
+
+// extensionName returns the extension type name of field, if any.
+//
+// Extension types that are not registered with the Arrow library are decoded as their storage type,
+// and their name is only available in the field metadata.
+func extensionName(field arrow.Field) string {
+	if ext, ok := field.Type.(arrow.ExtensionType); ok {
+		return ext.ExtensionName()
+	}
+	if i := field.Metadata.FindKey(ipc.ExtensionTypeKeyName); i >= 0 {
+		return field.Metadata.Values()[i]
+	}
+	return ""
+}
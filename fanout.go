@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v15/arrow/util"
+)
+
+// fanoutResult is the outcome of fetching data at a given level of parallelism.
+type fanoutResult struct {
+	Parallelism int
+	Elapsed     time.Duration
+	Rows        int64
+	Bytes       int64
+}
+
+func (r fanoutResult) rowsPerSec() float64 {
+	return float64(r.Rows) / r.Elapsed.Seconds()
+}
+
+func (r fanoutResult) bytesPerSec() float64 {
+	return float64(r.Bytes) / r.Elapsed.Seconds()
+}
+
+// runPartitions executes query once and fetches its endpoints with up to n concurrent DoGets.
+func runPartitions(ctx context.Context, c *flightsql.Client, query string, n int) (fanoutResult, error) {
+	res := fanoutResult{Parallelism: n}
+	start := time.Now()
+	info, err := c.Execute(ctx, query)
+	if err != nil {
+		return res, err
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	slots := make(chan struct{}, n)
+	for _, endpoint := range info.Endpoint {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(endpoint *flight.FlightEndpoint) {
+			defer func() { wg.Done(); <-slots }()
+			rows, bytes, err := fetchEndpoint(ctx, c, endpoint)
+			mu.Lock()
+			res.Rows, res.Bytes = res.Rows+rows, res.Bytes+bytes
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}(endpoint)
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+	if len(info.Endpoint) < n {
+		res.Parallelism = len(info.Endpoint)
+	}
+	return res, firstErr
+}
+
+// runIndependent runs n copies of query at once, each fetching its endpoints in turn.
+func runIndependent(ctx context.Context, c *flightsql.Client, query string, n int) (fanoutResult, error) {
+	res := fanoutResult{Parallelism: n}
+	start := time.Now()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rows, bytes, err := fetchQuery(ctx, c, query)
+			mu.Lock()
+			res.Rows, res.Bytes = res.Rows+rows, res.Bytes+bytes
+			if firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+	return res, firstErr
+}
+
+// fetchQuery executes query and fetches its endpoints one after the other.
+func fetchQuery(ctx context.Context, c *flightsql.Client, query string) (rows, bytes int64, err error) {
+	info, err := c.Execute(ctx, query)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, endpoint := range info.Endpoint {
+		r, b, err := fetchEndpoint(ctx, c, endpoint)
+		rows, bytes = rows+r, bytes+b
+		if err != nil {
+			return rows, bytes, err
+		}
+	}
+	return rows, bytes, nil
+}
+
+// fetchEndpoint fetches the data of endpoint, returning the number of rows and bytes received.
+func fetchEndpoint(ctx context.Context, c *flightsql.Client, endpoint *flight.FlightEndpoint) (rows, bytes int64, err error) {
+	reader, err := doGetEndpoint(ctx, c, endpoint)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Release()
+	for reader.Next() {
+		rows += reader.Record().NumRows()
+		bytes += util.TotalRecordSize(reader.Record())
+	}
+	return rows, bytes, reader.Err()
+}
+
+// runFanout contrasts fetching the partitions of one query in parallel with running independent queries
+// in parallel, doubling the parallelism up to max, and reports where each stops scaling.
+func runFanout(ctx context.Context, c *flightsql.Client, query string, max, count int) error {
+	modes := []struct {
+		name string
+		run  func(context.Context, *flightsql.Client, string, int) (fanoutResult, error)
+	}{
+		{"partitions", runPartitions},
+		{"queries", runIndependent},
+	}
+
+	table := newStatsTable("Mode", "Parallelism", "Elapsed", "Rows/s", "MB/s")
+	var summary []string
+	for _, mode := range modes {
+		var results []fanoutResult
+		for n := 1; n <= max; n *= 2 {
+			// keep the fastest of count runs, the least disturbed by noise
+			var best fanoutResult
+			for i := 0; i < count; i++ {
+				res, err := mode.run(ctx, c, query, n)
+				if err != nil {
+					return fmt.Errorf("%s at parallelism %d: %w", mode.name, n, err)
+				}
+				if i == 0 || res.Elapsed < best.Elapsed {
+					best = res
+				}
+			}
+			if best.Parallelism < n {
+				// the query has fewer endpoints than n, more DoGets can't go any faster
+				break
+			}
+			results = append(results, best)
+			table.Append([]string{mode.name, fmt.Sprint(best.Parallelism), best.Elapsed.String(),
+				fmt.Sprintf("%.1f", best.rowsPerSec()), fmt.Sprintf("%.2f", best.bytesPerSec()/1e6)})
+		}
+		summary = append(summary, describeSaturation(mode.name, results, max))
+	}
+	table.Render()
+	fmt.Println()
+	for _, s := range summary {
+		fmt.Println(s)
+	}
+	return nil
+}
+
+// describeSaturation names the parallelism at which throughput stops improving by at least 10%.
+func describeSaturation(mode string, results []fanoutResult, max int) string {
+	for i := 1; i < len(results); i++ {
+		if results[i].bytesPerSec() < results[i-1].bytesPerSec()*1.1 {
+			return fmt.Sprintf("%s: saturated at parallelism %d (%.2f MB/s)", mode, results[i-1].Parallelism, results[i-1].bytesPerSec()/1e6)
+		}
+	}
+	last := results[len(results)-1]
+	if last.Parallelism*2 <= max {
+		return fmt.Sprintf("%s: limited to parallelism %d by the number of endpoints (%.2f MB/s)", mode, last.Parallelism, last.bytesPerSec()/1e6)
+	}
+	return fmt.Sprintf("%s: still scaling at parallelism %d (%.2f MB/s)", mode, last.Parallelism, last.bytesPerSec()/1e6)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// serverFingerprint identifies the backend that served a query.
+type serverFingerprint struct {
+	Name       string
+	Version    string
+	Peer       string
+	TLSSubject string
+}
+
+// fetchFingerprint asks the server for its name and version, noting the peer address and TLS certificate
+// of the connection answering. It returns whatever it could find out, along with the first error.
+func fetchFingerprint(ctx context.Context, c *flightsql.Client) (serverFingerprint, error) {
+	var (
+		fp serverFingerprint
+		p  peer.Peer
+	)
+	info, err := c.GetSqlInfo(ctx, []flightsql.SqlInfo{flightsql.SqlInfoFlightSqlServerName, flightsql.SqlInfoFlightSqlServerVersion}, grpc.Peer(&p))
+	if p.Addr != nil {
+		fp.Peer = p.Addr.String()
+	}
+	if tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(tlsInfo.State.PeerCertificates) > 0 {
+		fp.TLSSubject = tlsInfo.State.PeerCertificates[0].Subject.String()
+	}
+	if err != nil {
+		return fp, err
+	}
+
+	_, err = forEachRecord(ctx, c, info, func(record arrow.Record) error {
+		decoded, err := decodeSqlInfo(record)
+		if err != nil {
+			return err
+		}
+		defer decoded.Release()
+		var o renderOptions
+		for r := 0; r < int(decoded.NumRows()); r++ {
+			name, err := o.renderText(decoded.Column(0), r)
+			if err != nil {
+				return err
+			}
+			value, err := o.renderText(decoded.Column(1), r)
+			if err != nil {
+				return err
+			}
+			switch name {
+			case flightsql.SqlInfoFlightSqlServerName.String():
+				fp.Name = value
+			case flightsql.SqlInfoFlightSqlServerVersion.String():
+				fp.Version = value
+			}
+		}
+		return nil
+	})
+	return fp, err
+}
+
+func (fp serverFingerprint) print(w io.Writer) {
+	server := strings.TrimSpace(fp.Name + " " + fp.Version)
+	if server == "" {
+		server = "unknown"
+	}
+	fmt.Fprintf(w, "Server: %s\n", server)
+	if fp.Peer != "" {
+		fmt.Fprintf(w, "Peer: %s\n", fp.Peer)
+	}
+	if fp.TLSSubject != "" {
+		fmt.Fprintf(w, "TLS subject: %s\n", fp.TLSSubject)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// rowFilter keeps the rows where a column, or any column, matches a regular expression.
+// Values are matched as rendered in the output.
+type rowFilter struct {
+	expr string
+	re   *regexp.Regexp
+	// column is the name of the column matched, or empty to match any column.
+	column string
+}
+
+// parseGrep parses a --grep argument of the form pattern or pattern:column. Since the pattern itself
+// may contain colons, a :suffix is only taken as a column if the results have a column with that name;
+// this is decided when the first record is received.
+func parseGrep(s string) (*rowFilter, error) {
+	re, err := regexp.Compile(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --grep pattern: %w", err)
+	}
+	return &rowFilter{expr: s, re: re}, nil
+}
+
+// resolve picks the column to match among the columns of header.
+func (f *rowFilter) resolve(header []string) error {
+	i := strings.LastIndex(f.expr, ":")
+	if i < 0 || columnIndex(header, f.expr[i+1:]) < 0 {
+		return nil
+	}
+	re, err := regexp.Compile(f.expr[:i])
+	if err != nil {
+		return fmt.Errorf("invalid --grep pattern: %w", err)
+	}
+	f.re, f.column = re, f.expr[i+1:]
+	return nil
+}
+
+// filter returns a record with the matching rows of record.
+func (f *rowFilter) filter(record arrow.Record, opts renderOptions) (arrow.Record, error) {
+	header := getHeader(record)
+	if f.expr != "" {
+		if err := f.resolve(header); err != nil {
+			return nil, err
+		}
+		f.expr = ""
+	}
+	cols := make([]int, 0, len(header))
+	if f.column != "" {
+		c := columnIndex(header, f.column)
+		if c < 0 {
+			return nil, fmt.Errorf("unknown column %q", f.column)
+		}
+		cols = append(cols, c)
+	} else {
+		for c := range header {
+			cols = append(cols, c)
+		}
+	}
+
+	return selectRows(record, func(r int) (bool, error) {
+		return f.matchRow(record, cols, r, opts)
+	})
+}
+
+// selectRows returns a record with the rows of record for which keep returns true.
+func selectRows(record arrow.Record, keep func(row int) (bool, error)) (arrow.Record, error) {
+	// collect the runs of consecutive kept rows as slices of record
+	var runs []arrow.Record
+	defer func() {
+		for _, r := range runs {
+			r.Release()
+		}
+	}()
+	start := -1
+	for r := 0; r <= int(record.NumRows()); r++ {
+		kept := false
+		if r < int(record.NumRows()) {
+			var err error
+			if kept, err = keep(r); err != nil {
+				return nil, err
+			}
+		}
+		if kept && start < 0 {
+			start = r
+		} else if !kept && start >= 0 {
+			runs = append(runs, record.NewSlice(int64(start), int64(r)))
+			start = -1
+		}
+	}
+
+	if len(runs) == 1 {
+		runs[0].Retain()
+		return runs[0], nil
+	}
+	if len(runs) == 0 {
+		return record.NewSlice(0, 0), nil
+	}
+	columns := make([]arrow.Array, record.NumCols())
+	var rows int64
+	for _, r := range runs {
+		rows += r.NumRows()
+	}
+	for c := range columns {
+		parts := make([]arrow.Array, len(runs))
+		for i, r := range runs {
+			parts[i] = r.Column(c)
+		}
+		col, err := array.Concatenate(parts, memory.DefaultAllocator)
+		if err != nil {
+			return nil, err
+		}
+		defer col.Release()
+		columns[c] = col
+	}
+	return array.NewRecord(record.Schema(), columns, rows), nil
+}
+
+func (f *rowFilter) matchRow(record arrow.Record, cols []int, r int, opts renderOptions) (bool, error) {
+	for _, c := range cols {
+		s, err := opts.renderColumn(record.Schema().Field(c), record.Column(c), r)
+		if err != nil {
+			return false, err
+		}
+		if f.re.MatchString(s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
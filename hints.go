@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errWarmup marks the failures of the warmup request, which is only there to measure the other requests better.
+var errWarmup = errors.New("warmup failed")
+
+// withHint appends to err an explanation of what to try next, for the most common failures.
+func withHint(err error) error {
+	if hint := errorHint(err); hint != "" {
+		return fmt.Errorf("%w\nhint: %s", err, hint)
+	}
+	return err
+}
+
+func errorHint(err error) string {
+	switch status.Code(err) {
+	case codes.Unauthenticated:
+		return "check --token (or FLIGHT_CLUB_TOKEN), --username/--password or the credential helper"
+	case codes.PermissionDenied:
+		return "check --db: the credentials may not grant access to this database"
+	case codes.Unimplemented:
+		if strings.Contains(status.Convert(err).Message(), "Decompressor is not installed") {
+			return "the server doesn't support this --compression: try --compression none"
+		}
+		if errors.Is(err, errWarmup) {
+			return "the server doesn't implement GetCatalogs, used to warm up the connection: try --warmup=never (or --warmup=auto)"
+		}
+	case codes.ResourceExhausted:
+		if strings.Contains(status.Convert(err).Message(), "larger than max") {
+			return "a response exceeded the gRPC message size limit: raise --max-recv-msg-size (e.g. 64MiB)"
+		}
+	case codes.Unavailable:
+		if strings.Contains(status.Convert(err).Message(), "too_many_pings") {
+			return "the server rejects pings this frequent: raise --keepalive-time, or set it to 0"
+		}
+		return "check --url; flightclub doctor diagnoses connection problems step by step"
+	}
+	return ""
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"golang.org/x/term"
+)
+
+// histDefaultBuckets is the number of buckets of --hist when not given.
+const histDefaultBuckets = 10
+
+// histogram collects the values of a column, printed as a histogram after the results: numeric values are
+// counted in equal-width buckets, other values by frequency (the most frequent ones, then the rest).
+type histogram struct {
+	column  string
+	buckets int
+
+	numeric bool
+	nulls   int64
+	values  []float64
+	counts  map[string]int64
+	rows    int64
+}
+
+// parseHist parses a --hist argument of the form column[:buckets].
+func parseHist(s string) (*histogram, error) {
+	h := &histogram{column: s, buckets: histDefaultBuckets, counts: map[string]int64{}}
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		// a colon not followed by a number is part of the column name
+		if n, err := strconv.Atoi(s[i+1:]); err == nil {
+			if n < 1 {
+				return nil, fmt.Errorf("invalid --hist buckets in %q", s)
+			}
+			h.column, h.buckets = s[:i], n
+		}
+	}
+	return h, nil
+}
+
+func (h *histogram) add(record arrow.Record) error {
+	c := columnIndex(getHeader(record), h.column)
+	if c < 0 {
+		return fmt.Errorf("unknown --hist column %q", h.column)
+	}
+	field, col := record.Schema().Field(c), record.Column(c)
+	id := field.Type.ID()
+	h.numeric = arrow.IsInteger(id) || arrow.IsFloating(id) || arrow.IsDecimal(id)
+	for r := 0; r < col.Len(); r++ {
+		h.rows++
+		if col.IsNull(r) {
+			h.nulls++
+			continue
+		}
+		s, err := renderOptions{}.renderColumn(field, col, r)
+		if err != nil {
+			return err
+		}
+		if h.numeric {
+			v, _ := strconv.ParseFloat(s, 64)
+			h.values = append(h.values, v)
+		} else {
+			h.counts[s]++
+		}
+	}
+	return nil
+}
+
+// histBar is a line of a histogram.
+type histBar struct {
+	label string
+	count int64
+}
+
+func (h *histogram) bars() []histBar {
+	if !h.numeric {
+		var bars []histBar
+		for v, n := range h.counts {
+			bars = append(bars, histBar{v, n})
+		}
+		sort.Slice(bars, func(i, j int) bool {
+			if bars[i].count != bars[j].count {
+				return bars[i].count > bars[j].count
+			}
+			return bars[i].label < bars[j].label
+		})
+		if len(bars) > h.buckets {
+			other := histBar{label: fmt.Sprintf("(%d others)", len(bars)-h.buckets)}
+			for _, b := range bars[h.buckets:] {
+				other.count += b.count
+			}
+			bars = append(bars[:h.buckets], other)
+		}
+		return bars
+	}
+
+	lo, hi := valueRange(h.values)
+	if math.IsNaN(lo) {
+		return nil
+	}
+	buckets := h.buckets
+	if hi == lo {
+		buckets = 1
+	}
+	bars := make([]histBar, buckets)
+	step := (hi - lo) / float64(buckets)
+	for i := range bars {
+		closing := ")"
+		if i == buckets-1 {
+			closing = "]"
+		}
+		bars[i].label = fmt.Sprintf("[%s, %s%s", formatFloat(lo+float64(i)*step), formatFloat(lo+float64(i+1)*step), closing)
+	}
+	for _, v := range h.values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		i := buckets - 1
+		if step > 0 {
+			i = int((v - lo) / step)
+		}
+		if i >= buckets {
+			i = buckets - 1
+		}
+		bars[i].count++
+	}
+	return bars
+}
+
+// print writes the histogram, with bars as wide as the terminal allows.
+func (h *histogram) print(w io.Writer) {
+	fmt.Fprintf(w, "%s: %d rows, %d NULLs\n", h.column, h.rows, h.nulls)
+	printBars(w, h.bars())
+}
+
+// printBars writes a labeled horizontal bar per count, scaled to fit the terminal width.
+func printBars(w io.Writer, bars []histBar) {
+	label, count, max := 0, 0, int64(0)
+	for _, b := range bars {
+		label = maxInt(label, len(b.label))
+		count = maxInt(count, len(strconv.FormatInt(b.count, 10)))
+		if b.count > max {
+			max = b.count
+		}
+	}
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	barWidth := maxInt(width-label-count-4, 10)
+	for _, b := range bars {
+		n := 0
+		if max > 0 {
+			n = int(math.Round(float64(b.count) / float64(max) * float64(barWidth)))
+		}
+		line := fmt.Sprintf("  %-*s %*d %s", label, b.label, count, b.count, strings.Repeat("█", n))
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
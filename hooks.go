@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// hookEvent describes a query to pre/post query hooks.
+type hookEvent struct {
+	Phase   string
+	URL     string
+	DB      string
+	Query   string
+	Timings Timings
+	Err     error
+}
+
+// env returns the hook event as environment variables.
+func (e hookEvent) env() []string {
+	env := []string{
+		"FLIGHT_CLUB_HOOK=" + e.Phase,
+		"FLIGHT_CLUB_URL=" + e.URL,
+		"FLIGHT_CLUB_DB=" + e.DB,
+		"FLIGHT_CLUB_QUERY=" + e.Query,
+	}
+	if e.Phase == "post" {
+		status, errText := 0, ""
+		if e.Err != nil {
+			status, errText = 1, e.Err.Error()
+		}
+		env = append(env,
+			fmt.Sprintf("FLIGHT_CLUB_EXIT_STATUS=%d", status),
+			"FLIGHT_CLUB_ERROR="+errText,
+			"FLIGHT_CLUB_WARMUP="+e.Timings.Warmup.String(),
+			"FLIGHT_CLUB_EXECUTE="+e.Timings.Execute.String(),
+			"FLIGHT_CLUB_DOGET="+e.Timings.DoGet.String(),
+			"FLIGHT_CLUB_TOTAL="+e.Timings.Total().String(),
+		)
+	}
+	return env
+}
+
+// runHooks runs each hook in turn, stopping at the first failure.
+//
+// A hook is either a shell command, which sees the event as FLIGHT_CLUB_* environment variables,
+// or one of the built-in actions:
+//
+//	@bell           ring the terminal bell
+//	@notify         ring the bell and show a desktop notification (post-query only)
+//	@ledger:<file>  append the event as a JSON line to file
+func runHooks(hooks []string, e hookEvent) error {
+	for _, h := range hooks {
+		if err := runHook(h, e); err != nil {
+			return fmt.Errorf("%s-query hook %q: %w", e.Phase, h, err)
+		}
+	}
+	return nil
+}
+
+func runHook(hook string, e hookEvent) error {
+	switch {
+	case hook == "@bell":
+		_, err := fmt.Fprint(os.Stderr, "\a")
+		return err
+	case hook == "@notify":
+		if e.Phase != "post" {
+			return nil
+		}
+		return notifyDone(e)
+	case strings.HasPrefix(hook, "@ledger:"):
+		return appendLedger(strings.TrimPrefix(hook, "@ledger:"), e)
+	case strings.HasPrefix(hook, "@"):
+		return fmt.Errorf("unknown built-in hook")
+	}
+
+	cmd := exec.Command("/bin/sh", "-c", hook)
+	cmd.Env = append(os.Environ(), e.env()...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func appendLedger(filename string, e hookEvent) error {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	entry := struct {
+		Time    time.Time `json:"time"`
+		Phase   string    `json:"phase"`
+		URL     string    `json:"url"`
+		DB      string    `json:"db"`
+		Query   string    `json:"query"`
+		Warmup  float64   `json:"warmup_seconds"`
+		Execute float64   `json:"execute_seconds"`
+		DoGet   float64   `json:"doget_seconds"`
+		Total   float64   `json:"total_seconds"`
+		Error   string    `json:"error,omitempty"`
+	}{
+		Time:    time.Now(),
+		Phase:   e.Phase,
+		URL:     e.URL,
+		DB:      e.DB,
+		Query:   e.Query,
+		Warmup:  e.Timings.Warmup.Seconds(),
+		Execute: e.Timings.Execute.Seconds(),
+		DoGet:   e.Timings.DoGet.Seconds(),
+		Total:   e.Timings.Total().Seconds(),
+	}
+	if e.Err != nil {
+		entry.Error = e.Err.Error()
+	}
+	if err := json.NewEncoder(f).Encode(entry); err != nil {
+		return err
+	}
+	return f.Close()
+}
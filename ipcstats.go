@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v15/arrow/ipc"
+	flatbuffers "github.com/google/flatbuffers/go"
+)
+
+// ipcStats records how the server encoded the IPC messages of the results: whether record batches
+// were compressed, and with which codec, and whether dictionaries were sent as deltas.
+// The reader decodes all of these transparently, this only makes them visible with --verbose.
+// A nil ipcStats records nothing.
+type ipcStats struct {
+	mu           sync.Mutex
+	batches      int
+	compressed   map[string]int
+	dictionaries int
+	deltas       int
+}
+
+// Values of the Arrow IPC flatbuffers schema (format/Message.fbs).
+const (
+	headerDictionaryBatch = 2
+	headerRecordBatch     = 3
+)
+
+var compressionCodecs = []string{"lz4_frame", "zstd"}
+
+// observe decodes the header of an IPC message, i.e. a flatbuffers Message table.
+func (s *ipcStats) observe(header []byte) {
+	if s == nil || len(header) < flatbuffers.SizeUOffsetT {
+		return
+	}
+	msg := flatbuffers.Table{Bytes: header, Pos: flatbuffers.GetUOffsetT(header)}
+	o := flatbuffers.UOffsetT(msg.Offset(8))
+	if o == 0 {
+		return
+	}
+	var body flatbuffers.Table
+	msg.Union(&body, o)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch msg.GetByteSlot(6, 0) {
+	case headerRecordBatch:
+		s.batches++
+		if codec := batchCompression(body); codec != "" {
+			if s.compressed == nil {
+				s.compressed = map[string]int{}
+			}
+			s.compressed[codec]++
+		}
+	case headerDictionaryBatch:
+		s.dictionaries++
+		if body.GetBoolSlot(8, false) {
+			s.deltas++
+		}
+	}
+}
+
+// batchCompression returns the compression codec of a RecordBatch table, or "" if it isn't compressed.
+func batchCompression(batch flatbuffers.Table) string {
+	o := flatbuffers.UOffsetT(batch.Offset(10))
+	if o == 0 {
+		return ""
+	}
+	compression := flatbuffers.Table{Bytes: batch.Bytes, Pos: batch.Indirect(o + batch.Pos)}
+	if c := int(compression.GetByteSlot(4, 0)); c < len(compressionCodecs) {
+		return compressionCodecs[c]
+	}
+	return fmt.Sprintf("codec %d", compression.GetByteSlot(4, 0))
+}
+
+func (s *ipcStats) print(w io.Writer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.compressed) == 0 {
+		fmt.Fprintf(w, "IPC compression: none (%d record batches)\n", s.batches)
+	}
+	for codec, n := range s.compressed {
+		fmt.Fprintf(w, "IPC compression: %s (%d of %d record batches)\n", codec, n, s.batches)
+	}
+	if s.dictionaries > 0 {
+		fmt.Fprintf(w, "Dictionary batches: %d (%d deltas)\n", s.dictionaries, s.deltas)
+	}
+}
+
+// observedStream passes the messages of a DoGet stream through, recording their encoding
+// and dumping them, if enabled.
+type observedStream struct {
+	flight.DataStreamReader
+	stats *ipcStats
+	dump  *flightDataDump
+	id    int
+}
+
+func (o *observedStream) Recv() (*flight.FlightData, error) {
+	data, err := o.DataStreamReader.Recv()
+	if err == nil {
+		o.stats.observe(data.DataHeader)
+		if dumpErr := o.dump.write(o.id, data); dumpErr != nil {
+			return nil, dumpErr
+		}
+	}
+	return data, err
+}
+
+// doGet is like c.DoGet, but records the encoding of the messages if ctx carries ipcStats,
+// and dumps them if ctx carries a flightDataDump.
+func doGet(ctx context.Context, c *flightsql.Client, ticket *flight.Ticket) (*flight.Reader, error) {
+	stats, dump := ipcStatsFrom(ctx), flightDataDumpFrom(ctx)
+	if stats == nil && dump == nil {
+		return c.DoGet(ctx, ticket)
+	}
+	stream, err := c.Client.DoGet(ctx, ticket)
+	if err != nil {
+		return nil, err
+	}
+	return flight.NewRecordReader(&observedStream{DataStreamReader: stream, stats: stats, dump: dump, id: dump.newStream(ticket)},
+		ipc.WithAllocator(c.Alloc))
+}
+
+type ipcStatsKey struct{}
+
+func withIPCStats(ctx context.Context, s *ipcStats) context.Context {
+	return context.WithValue(ctx, ipcStatsKey{}, s)
+}
+
+// ipcStatsFrom returns the IPC statistics collector of ctx, or nil if they aren't collected.
+func ipcStatsFrom(ctx context.Context) *ipcStats {
+	s, _ := ctx.Value(ipcStatsKey{}).(*ipcStats)
+	return s
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/compute"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/apache/arrow/go/v15/parquet/file"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+// lookupTable is a local table joined with the results by --join: each row of the results is extended with
+// the columns of the lookup row having the same key, or with NULLs if there's none (a left join).
+// Keys are compared as rendered in the output.
+type lookupTable struct {
+	table arrow.Record
+	// on is the key column in the results, and key the key column in the table
+	on, key string
+	// prefix qualifies the appended columns named like a column of the results
+	prefix string
+	index  map[string]int64
+	// columns are the columns of table appended to the results
+	columns []int
+}
+
+// loadLookupTable reads the CSV, JSON (*.json) or Parquet (*.parquet) file filename, indexing it on
+// the key named by on: either a column present in both the results and the file, or result_column=file_column.
+func loadLookupTable(filename, on string, opts renderOptions) (*lookupTable, error) {
+	base := filepath.Base(filename)
+	t := &lookupTable{on: on, key: on, prefix: strings.TrimSuffix(base, filepath.Ext(base)), index: map[string]int64{}}
+	if i := strings.Index(on, "="); i >= 0 {
+		t.on, t.key = on[:i], on[i+1:]
+	}
+
+	var err error
+	if strings.EqualFold(filepath.Ext(filename), ".parquet") {
+		t.table, err = readParquetRecord(filename)
+	} else {
+		t.table, err = readStringRecord(filename)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	header := getHeader(t.table)
+	k := columnIndex(header, t.key)
+	if k < 0 {
+		return nil, fmt.Errorf("%s has no %q column", filename, t.key)
+	}
+	for c := range header {
+		if c != k {
+			t.columns = append(t.columns, c)
+		}
+	}
+	field, keys := t.table.Schema().Field(k), t.table.Column(k)
+	duplicates := 0
+	for r := 0; r < int(t.table.NumRows()); r++ {
+		if keys.IsNull(r) {
+			continue
+		}
+		s, err := opts.renderColumn(field, keys, r)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := t.index[s]; ok {
+			duplicates++
+			continue
+		}
+		t.index[s] = int64(r)
+	}
+	if duplicates > 0 {
+		fmt.Fprintf(os.Stderr, "%s: ignoring %d rows with duplicate %s keys (the first one wins)\n", filename, duplicates, t.key)
+	}
+	return t, nil
+}
+
+// readStringRecord reads a CSV or JSON file (in the --params-file format) as a record of string columns.
+func readStringRecord(filename string) (arrow.Record, error) {
+	names, rows, err := readParamsFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		fields[i] = arrow.Field{Name: name, Type: arrow.BinaryTypes.String, Nullable: true}
+	}
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer b.Release()
+	for r, row := range rows {
+		if len(row) != len(names) {
+			return nil, fmt.Errorf("%s: row %d: expecting %d columns, got %d", filename, r+1, len(names), len(row))
+		}
+		for i, value := range row {
+			b.Field(i).(*array.StringBuilder).Append(value)
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+// readParquetRecord reads a whole Parquet file as a single record, preserving the column types.
+func readParquetRecord(filename string) (arrow.Record, error) {
+	f, err := file.OpenParquetFile(filename, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	defer f.Close()
+	fr, err := pqarrow.NewFileReader(f, pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	table, err := fr.ReadTable(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	defer table.Release()
+
+	cols := make([]arrow.Array, table.NumCols())
+	for c := range cols {
+		chunks := table.Column(c).Data().Chunks()
+		if len(chunks) == 1 {
+			chunks[0].Retain()
+			cols[c] = chunks[0]
+			continue
+		}
+		if cols[c], err = array.Concatenate(chunks, memory.DefaultAllocator); err != nil {
+			return nil, err
+		}
+	}
+	defer func() {
+		for _, col := range cols {
+			col.Release()
+		}
+	}()
+	return array.NewRecord(table.Schema(), cols, table.NumRows()), nil
+}
+
+// join returns record with the columns of the matching lookup rows appended. Lookup columns named like
+// a column of the results are prefixed with the name of the file (e.g. hosts.name for hosts.csv).
+func (t *lookupTable) join(record arrow.Record, opts renderOptions) (arrow.Record, error) {
+	header := getHeader(record)
+	k := columnIndex(header, t.on)
+	if k < 0 {
+		return nil, fmt.Errorf("unknown --on column %q", t.on)
+	}
+	field, keys := record.Schema().Field(k), record.Column(k)
+	ib := array.NewInt64Builder(memory.DefaultAllocator)
+	defer ib.Release()
+	for r := 0; r < int(record.NumRows()); r++ {
+		if keys.IsNull(r) {
+			ib.AppendNull()
+			continue
+		}
+		s, err := opts.renderColumn(field, keys, r)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := t.index[s]; ok {
+			ib.Append(i)
+		} else {
+			ib.AppendNull()
+		}
+	}
+	indices := ib.NewArray()
+	defer indices.Release()
+
+	fields := append([]arrow.Field{}, record.Schema().Fields()...)
+	cols := append([]arrow.Array{}, record.Columns()...)
+	for _, c := range t.columns {
+		taken, err := compute.TakeArray(context.Background(), t.table.Column(c), indices)
+		if err != nil {
+			return nil, err
+		}
+		defer taken.Release()
+		f := t.table.Schema().Field(c)
+		if columnIndex(header, f.Name) >= 0 {
+			f.Name = t.prefix + "." + f.Name
+		}
+		f.Nullable = true
+		fields, cols = append(fields, f), append(cols, taken)
+	}
+	metadata := record.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &metadata), cols, record.NumRows()), nil
+}
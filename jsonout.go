@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+)
+
+// jsonWriter writes each row as a JSON object, either as elements of a JSON array
+// or one per line (NDJSON).
+type jsonWriter struct {
+	w     *bufio.Writer
+	opts  printOptions
+	lines bool
+	rows  int64
+}
+
+func newJSONWriter(w io.Writer, opts printOptions, lines bool) *jsonWriter {
+	return &jsonWriter{w: bufio.NewWriter(w), opts: opts, lines: lines}
+}
+
+func (j *jsonWriter) WriteRecord(record arrow.Record) error {
+	schema := record.Schema()
+	var buf bytes.Buffer
+	for r := 0; r < int(record.NumRows()); r++ {
+		buf.Reset()
+		buf.WriteByte('{')
+		for c := 0; c < int(record.NumCols()); c++ {
+			if c > 0 {
+				buf.WriteByte(',')
+			}
+			key, err := json.Marshal(record.ColumnName(c))
+			if err != nil {
+				return err
+			}
+			v, err := j.opts.jsonValue(schema.Field(c), record.Column(c), r)
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+
+		switch {
+		case j.lines:
+		case j.rows == 0:
+			j.w.WriteString("[\n")
+		default:
+			j.w.WriteString(",\n")
+		}
+		j.w.Write(buf.Bytes())
+		if j.lines {
+			j.w.WriteByte('\n')
+		}
+		j.rows++
+	}
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	if !j.lines {
+		if j.rows == 0 {
+			j.w.WriteString("[")
+		}
+		j.w.WriteString("\n]\n")
+	}
+	return j.w.Flush()
+}
+
+// jsonValue returns a value of a column as a Go value that marshals to the matching JSON type:
+// numbers (including decimals) and booleans are preserved (as are durations rendered as seconds or nanos),
+// binary data is base64 encoded (unless --binary-format is given) and everything else is rendered as text.
+func (o renderOptions) jsonValue(field arrow.Field, column arrow.Array, row int) (interface{}, error) {
+	if column.IsNull(row) {
+		return nil, nil
+	}
+	if extensionName(field) != "" {
+		return o.renderColumn(field, column, row)
+	}
+	switch typedColumn := column.(type) {
+	case *array.Float16:
+		return jsonFloat(float64(typedColumn.Value(row).Float32())), nil
+	case *array.Float32:
+		return jsonFloat(float64(typedColumn.Value(row))), nil
+	case *array.Float64:
+		return jsonFloat(typedColumn.Value(row)), nil
+	case *array.Uint8:
+		return typedColumn.Value(row), nil
+	case *array.Uint16:
+		return typedColumn.Value(row), nil
+	case *array.Uint32:
+		return typedColumn.Value(row), nil
+	case *array.Uint64:
+		return typedColumn.Value(row), nil
+	case *array.Int8:
+		return typedColumn.Value(row), nil
+	case *array.Int16:
+		return typedColumn.Value(row), nil
+	case *array.Int32:
+		return typedColumn.Value(row), nil
+	case *array.Int64:
+		return typedColumn.Value(row), nil
+	case *array.Duration:
+		unit := typedColumn.DataType().(*arrow.DurationType).Unit
+		d := time.Duration(typedColumn.Value(row)) * unit.Multiplier()
+		switch o.DurationFormat {
+		case "seconds":
+			return d.Seconds(), nil
+		case "nanos":
+			return d.Nanoseconds(), nil
+		}
+		return o.renderDuration(typedColumn.Value(row), unit), nil
+	case *array.Decimal128, *array.Decimal256:
+		// exact, unlike a float64
+		s, err := o.renderText(column, row)
+		return json.Number(s), err
+	case *array.String:
+		return typedColumn.Value(row), nil
+	case *array.Binary:
+		if o.BinaryFormat != "" {
+			return o.renderBinary(typedColumn.Value(row)), nil
+		}
+		return typedColumn.Value(row), nil
+	case *array.Boolean:
+		return typedColumn.Value(row), nil
+	case *array.RunEndEncoded:
+		return o.jsonValue(field, typedColumn.Values(), typedColumn.GetPhysicalIndex(row))
+	default:
+		return o.renderText(column, row)
+	}
+}
+
+// jsonFloat returns f, or its text form if it cannot be represented in JSON (NaN and infinities).
+func jsonFloat(f float64) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
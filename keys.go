@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// KeysCmd groups the subcommands inspecting the relationships between tables.
+type KeysCmd struct {
+	Primary  PrimaryKeysCmd    `cmd:"" help:"List the primary key columns of a table"`
+	Imported ImportedKeysCmd   `cmd:"" help:"List the foreign keys of a table (the primary keys it references)"`
+	Exported ExportedKeysCmd   `cmd:"" help:"List the foreign keys referencing the primary key of a table"`
+	Crossref CrossReferenceCmd `cmd:"" help:"List the foreign keys of a table referencing the primary key of another table"`
+}
+
+// tableRef is a table name given as [catalog.][schema.]table.
+type tableRef string
+
+func (t tableRef) ref() flightsql.TableRef {
+	parts := strings.Split(string(t), ".")
+	ref := flightsql.TableRef{Table: parts[len(parts)-1]}
+	if len(parts) >= 2 {
+		ref.DBSchema = &parts[len(parts)-2]
+	}
+	if len(parts) >= 3 {
+		catalog := strings.Join(parts[:len(parts)-2], ".")
+		ref.Catalog = &catalog
+	}
+	return ref
+}
+
+// PrimaryKeysCmd lists the primary key columns of a table.
+type PrimaryKeysCmd struct {
+	Table tableRef `arg:"" help:"Table, as [catalog.][schema.]table"`
+}
+
+func (cmd *PrimaryKeysCmd) Run(cli *Context) error {
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetPrimaryKeys(ctx, cmd.Table.ref())
+	}, printOptions{})
+}
+
+// ImportedKeysCmd lists the foreign keys of a table.
+type ImportedKeysCmd struct {
+	Table tableRef `arg:"" help:"Table, as [catalog.][schema.]table"`
+}
+
+func (cmd *ImportedKeysCmd) Run(cli *Context) error {
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetImportedKeys(ctx, cmd.Table.ref())
+	}, printOptions{})
+}
+
+// ExportedKeysCmd lists the foreign keys referencing a table.
+type ExportedKeysCmd struct {
+	Table tableRef `arg:"" help:"Table, as [catalog.][schema.]table"`
+}
+
+func (cmd *ExportedKeysCmd) Run(cli *Context) error {
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetExportedKeys(ctx, cmd.Table.ref())
+	}, printOptions{})
+}
+
+// CrossReferenceCmd lists the foreign keys of a table referencing another one.
+type CrossReferenceCmd struct {
+	PKTable tableRef `arg:"" name:"pk-table" help:"Table with the primary key, as [catalog.][schema.]table"`
+	FKTable tableRef `arg:"" name:"fk-table" help:"Table with the foreign key, as [catalog.][schema.]table"`
+}
+
+func (cmd *CrossReferenceCmd) Run(cli *Context) error {
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetCrossReference(ctx, cmd.PKTable.ref(), cmd.FKTable.ref())
+	}, printOptions{})
+}
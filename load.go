@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/olekukonko/tablewriter"
+)
+
+// rampStep is a period of constant concurrency in a load ramp.
+type rampStep struct {
+	Concurrency int
+	Duration    time.Duration
+}
+
+// rampSchedule is parsed from a comma separated list of concurrency:duration steps, e.g. "1:30s,10:60s,50:120s".
+type rampSchedule []rampStep
+
+func (r *rampSchedule) UnmarshalText(text []byte) error {
+	for _, s := range strings.Split(string(text), ",") {
+		c, d, ok := strings.Cut(s, ":")
+		if !ok {
+			return fmt.Errorf("invalid ramp step %q, expecting concurrency:duration", s)
+		}
+		concurrency, err := strconv.Atoi(c)
+		if err != nil || concurrency < 1 {
+			return fmt.Errorf("invalid concurrency in ramp step %q", s)
+		}
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return fmt.Errorf("invalid duration in ramp step %q: %w", s, err)
+		}
+		*r = append(*r, rampStep{Concurrency: concurrency, Duration: duration})
+	}
+	return nil
+}
+
+// loadResult is the outcome of driving a query at a fixed concurrency for some time.
+type loadResult struct {
+	Concurrency int
+	Elapsed     time.Duration
+	Latencies   []time.Duration
+	Errors      []string
+}
+
+func (r loadResult) requests() int {
+	return len(r.Latencies) + len(r.Errors)
+}
+
+func (r loadResult) throughput() float64 {
+	return float64(len(r.Latencies)) / r.Elapsed.Seconds()
+}
+
+func (r loadResult) errorRate() float64 {
+	if r.requests() == 0 {
+		return 0
+	}
+	return float64(len(r.Errors)) / float64(r.requests())
+}
+
+// runLoad runs query in a loop from concurrency goroutines, spread over the clients, until d has elapsed.
+// If rps is positive, the requests of all the goroutines together are paced to at most rps per second.
+func runLoad(ctx context.Context, clients []*flightsql.Client, query string, concurrency int, d time.Duration, rps float64) loadResult {
+	res := loadResult{Concurrency: concurrency}
+
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	start := time.Now()
+	deadline := start.Add(d)
+
+	var tokens <-chan time.Time
+	if rps > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rps))
+		defer ticker.Stop()
+		tokens = ticker.C
+	}
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(c *flightsql.Client) {
+			defer wg.Done()
+			// stop at the end of the step, or when interrupted (Ctrl-C, --deadline)
+			for time.Now().Before(deadline) && ctx.Err() == nil {
+				if tokens != nil {
+					select {
+					case <-tokens:
+					case <-time.After(time.Until(deadline)):
+						return
+					case <-ctx.Done():
+						return
+					}
+				}
+				before := time.Now()
+				_, _, err := runQuery(ctx, c, query)
+				latency := time.Since(before)
+				if ctx.Err() != nil {
+					// not a failure of the server
+					return
+				}
+
+				mu.Lock()
+				if err != nil {
+					res.Errors = append(res.Errors, err.Error())
+				} else {
+					res.Latencies = append(res.Latencies, latency)
+				}
+				mu.Unlock()
+			}
+		}(clients[i%len(clients)])
+	}
+	wg.Wait()
+	res.Elapsed = time.Since(start)
+
+	return res
+}
+
+// printLoad prints the outcome of a load test: throughput, latency percentiles, a latency histogram and the errors.
+func printLoad(res loadResult) {
+	st := newDurationStats(res.Latencies)
+	fmt.Printf("%d requests in %s at concurrency %d: %.1f req/s, %d errors (%.1f%%)\n\n",
+		res.requests(), res.Elapsed.Round(time.Millisecond), res.Concurrency, res.throughput(), len(res.Errors), 100*res.errorRate())
+
+	table := newStatsTable("Min", "Mean", "P50", "P95", "P99", "Max")
+	table.Append([]string{st.Min.String(), st.Mean.String(), st.P50.String(), st.P95.String(), st.P99.String(), st.Max.String()})
+	table.Render()
+
+	if len(res.Latencies) > 0 {
+		fmt.Println()
+		printHistogram(res.Latencies)
+	}
+
+	if len(res.Errors) > 0 {
+		counts := map[string]int{}
+		for _, e := range res.Errors {
+			counts[e]++
+		}
+		msgs := make([]string, 0, len(counts))
+		for msg := range counts {
+			msgs = append(msgs, msg)
+		}
+		sort.Slice(msgs, func(i, j int) bool { return counts[msgs[i]] > counts[msgs[j]] })
+
+		fmt.Println()
+		table := newStatsTable("Count", "Error")
+		table.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+		for _, msg := range msgs {
+			table.Append([]string{strconv.Itoa(counts[msg]), msg})
+		}
+		table.Render()
+	}
+}
+
+// histogramBounds are the upper bounds of the latency histogram buckets, in a 1-2-5 progression.
+var histogramBounds = func() []time.Duration {
+	var bounds []time.Duration
+	for d := time.Millisecond; d <= 10*time.Minute; d *= 10 {
+		bounds = append(bounds, d, 2*d, 5*d)
+	}
+	return bounds
+}()
+
+// printHistogram prints the distribution of latencies as a bar chart, from the first to the last non-empty bucket.
+func printHistogram(latencies []time.Duration) {
+	const width = 40
+
+	counts := make([]int, len(histogramBounds)+1)
+	for _, l := range latencies {
+		counts[sort.Search(len(histogramBounds), func(i int) bool { return l <= histogramBounds[i] })]++
+	}
+	first, last, peak := -1, 0, 0
+	for i, n := range counts {
+		if n == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		last = i
+		if n > peak {
+			peak = n
+		}
+	}
+
+	for i := first; i <= last; i++ {
+		label := "> " + histogramBounds[len(histogramBounds)-1].String()
+		if i < len(histogramBounds) {
+			label = "<= " + histogramBounds[i].String()
+		}
+		bar := strings.Repeat("#", (counts[i]*width+peak-1)/peak)
+		fmt.Printf("%10s %-*s %d\n", label, width, bar, counts[i])
+	}
+}
+
+// runRamp runs each step of the schedule in turn and reports the latency/error knee.
+func runRamp(ctx context.Context, c *flightsql.Client, query string, schedule rampSchedule) []loadResult {
+	var results []loadResult
+	for _, step := range schedule {
+		res := runLoad(ctx, []*flightsql.Client{c}, query, step.Concurrency, step.Duration, 0)
+		results = append(results, res)
+		if ctx.Err() != nil {
+			// interrupted: report the steps run so far
+			break
+		}
+	}
+
+	table := newStatsTable("Concurrency", "Requests", "Errors", "Req/s", "P50", "P95", "P99")
+	for _, res := range results {
+		st := newDurationStats(res.Latencies)
+		table.Append([]string{
+			strconv.Itoa(res.Concurrency),
+			strconv.Itoa(res.requests()),
+			fmt.Sprintf("%d (%.1f%%)", len(res.Errors), 100*res.errorRate()),
+			fmt.Sprintf("%.1f", res.throughput()),
+			st.P50.String(), st.P95.String(), st.P99.String(),
+		})
+	}
+	table.Render()
+
+	if knee := findKnee(results); knee >= 0 {
+		fmt.Printf("\nKnee at concurrency %d: throughput stops scaling or errors appear\n", results[knee].Concurrency)
+	} else {
+		fmt.Printf("\nNo knee found: throughput kept scaling up to concurrency %d\n", results[len(results)-1].Concurrency)
+	}
+	return results
+}
+
+// findKnee returns the index of the first step that either produced errors
+// or didn't improve throughput by at least 10% over the previous step; -1 if none.
+func findKnee(results []loadResult) int {
+	for i, res := range results {
+		if len(res.Errors) > 0 {
+			return i
+		}
+		if i > 0 && res.throughput() < results[i-1].throughput()*1.1 {
+			return i
+		}
+	}
+	return -1
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"crypto"
 	"crypto/tls"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"math/rand"
 	"net/url"
 	"os"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
@@ -17,6 +20,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -36,77 +40,779 @@ type Context struct {
 
 // CLI contains the CLI parameters.
 type CLI struct {
-	URL   string `required:""`
-	DB    string `required:""`
+	// URL and DB are required, but checked by checkTarget since --dsn can set them too
+	URL   string `xor:"url" env:"FLIGHT_CLUB_URL"`
+	DB    string `xor:"db" env:"FLIGHT_CLUB_DB"`
 	Token string `env:"FLIGHT_CLUB_TOKEN"`
 
-	Headers    map[string]string `short:"H" env:"FLIGHT_CLUB_HEADERS"`
-	GenTraceId bool
+	DSN string `name:"dsn" xor:"url,db" env:"FLIGHT_CLUB_DSN" placeholder:"DSN" help:"Connection string, instead of --url and --db: flightsql://[TOKEN@]HOST[:PORT][/DB][?tls=BOOL&header.NAME=VALUE...]"`
 
-	Query QueryCmd `cmd:"" help:"query"`
+	Username string `env:"FLIGHT_CLUB_USERNAME" help:"Log in with the Flight basic auth handshake as this user, instead of using a token"`
+	Password string `env:"FLIGHT_CLUB_PASSWORD" help:"Password of --username"`
+
+	CredentialHelper string `env:"FLIGHT_CLUB_CREDENTIAL_HELPER" help:"Command whose stdout supplies the token (or a JSON object with token and headers)"`
+
+	CACert string `name:"cacert" type:"existingfile" help:"PEM file of the CA certificates trusted to verify the server, instead of the system ones"`
+	Cert   string `type:"existingfile" help:"PEM file of the client certificate presented to servers requiring mutual TLS"`
+	Key    string `type:"existingfile" help:"PEM file of the private key of --cert"`
+
+	InsecureSkipVerify bool   `help:"Don't verify the server certificate (for dev clusters with self-signed certificates)"`
+	TLSServerName      string `name:"tls-server-name" help:"Server name sent in the TLS handshake (SNI) and verified against the certificate, instead of the URL host"`
+
+	TokenExchangeURL      string `env:"FLIGHT_CLUB_TOKEN_EXCHANGE_URL" help:"OAuth 2.0 token exchange (RFC 8693) endpoint trading the token for a short-lived access token before connecting"`
+	TokenExchangeAudience string `help:"Audience requested from the token exchange endpoint"`
+
+	OAuthTokenURL     string   `name:"oauth-token-url" env:"FLIGHT_CLUB_OAUTH_TOKEN_URL" help:"OAuth 2.0 token endpoint issuing bearer tokens with the client credentials grant, refreshed automatically on expiry"`
+	OAuthClientID     string   `name:"oauth-client-id" env:"FLIGHT_CLUB_OAUTH_CLIENT_ID" help:"Client ID for --oauth-token-url"`
+	OAuthClientSecret string   `name:"oauth-client-secret" env:"FLIGHT_CLUB_OAUTH_CLIENT_SECRET" help:"Client secret for --oauth-token-url"`
+	OAuthScope        []string `name:"oauth-scope" help:"Scopes requested from --oauth-token-url"`
+
+	Profile string `env:"FLIGHT_CLUB_PROFILE" help:"Name of the config file profile providing default flag values"`
+
+	Headers      map[string]string `short:"H" env:"FLIGHT_CLUB_HEADERS"`
+	GenTraceId   bool              `help:"Send the requests in a new trace, printing its ID (with the TRACEPARENT environment variable set, they always join the trace of the caller)"`
+	TraceFormat  string            `enum:"uber,w3c,all" default:"all" help:"Trace headers sent: uber (influx-trace-id and uber-trace-id), w3c (traceparent and tracestate) or all"`
+	Verbose      bool              `short:"v" help:"Print the identity of the server (name, version, peer address and TLS certificate) in the summary"`
+	DebugGRPC    bool              `name:"debug-grpc" help:"Log every gRPC call to stderr: method, outgoing metadata (with credentials redacted), message sizes, status code and latency"`
+	StickyHeader []string          `placeholder:"KEY" help:"Capture the KEY header (or trailer) of the first response carrying it and send it back on all the following calls, for servers requiring sticky routing"`
+	ShowMetadata bool              `help:"Print the gRPC response headers and trailers of the Execute and DoGet calls (e.g. the query ID of the server) after the results"`
+
+	KeepaliveTime                time.Duration `help:"Ping the server after this long without activity, so that load balancers don't drop the connection during long queries; the server must allow pings this frequent (gRPC servers default to at most one every 5m) or it closes the connection (default: no pings)"`
+	KeepaliveTimeout             time.Duration `default:"20s" help:"Close the connection if a ping isn't answered within this time"`
+	KeepalivePermitWithoutStream bool          `help:"Also ping when no call is in progress"`
+
+	Compression string `enum:"none,gzip,zstd" default:"none" help:"Compress the gRPC messages with gzip or zstd, if the server supports it, reporting the bytes saved in the summary"`
+
+	MaxRecvMsgSize byteSize `placeholder:"SIZE" help:"Largest gRPC message accepted from the server (default 4MiB)"`
+
+	Warmup string `enum:"auto,always,never" default:"auto" help:"Send a dummy request before the first query of each connection to keep connection setup out of the timings: auto (skipped if the server doesn't implement it), always (before every query, failing if unimplemented) or never"`
+
+	Deadline time.Duration `help:"Bound the whole invocation (connect, warmup, execute, fetch and render) to this duration, reporting the active phase when it's exceeded"`
+
+	DumpFlightData string `name:"dump-flightdata" type:"path" placeholder:"DIR" help:"Write every raw FlightData message received by DoGet (header, body and app metadata) to files in DIR, byte for byte, for reporting protocol bugs"`
+
+	ProgressJSON int `placeholder:"FD" help:"Write machine-readable progress events as JSON lines to file descriptor FD (e.g. 3)"`
+
+	PreQueryHook  []string `sep:"none" help:"Shell command (or @bell, @ledger:<file>) run before each query"`
+	PostQueryHook []string `sep:"none" help:"Shell command (or @bell, @notify, @ledger:<file>) run after each query, with timings and exit status in FLIGHT_CLUB_* env vars"`
+	Notify        bool     `help:"Ring the bell and show a desktop notification when the query finishes or fails (same as --post-query-hook @notify)"`
+
+	// locations holds the credentials of endpoint locations, from the config file
+	locations map[string]LocationCredentials
+	// progress receives the progress events, if enabled with --progress-json
+	progress *progressWriter
+	// dump saves the raw FlightData messages, if enabled with --dump-flightdata
+	dump *flightDataDump
+	// base is the parent of all the request contexts, canceled on SIGINT or when the --deadline is exceeded
+	base context.Context
+	// phases tracks the progress of the invocation, if --deadline is set
+	phases *phaseTracker
+	// bearer and handshakeToken are obtained by the basic auth handshake, with --username
+	bearer         string
+	handshakeToken string
+	// oauth caches the access token from --oauth-token-url
+	oauth *oauthToken
+	// exchanged caches the access token from --token-exchange-url
+	exchanged *exchangedToken
+	// sticky holds the headers captured with --sticky-header, from the server at URL
+	sticky *stickyHeaders
+	// grpcLog logs the gRPC calls, with --debug-grpc
+	grpcLog *grpcDebugLog
+	// responses records the response metadata of the calls, with --show-metadata
+	responses *responseMetadata
+	// audit records the invocation, if audit_log is set in the config file
+	audit *auditLog
+	// wire counts the bytes received, with --compression
+	wire *wireStats
+	// warmed records the connections already warmed up
+	warmed map[*flightsql.Client]bool
+
+	Query    QueryCmd    `cmd:"" help:"query"`
+	Catalogs CatalogsCmd `cmd:"" help:"List catalogs"`
+	Schemas  SchemasCmd  `cmd:"" help:"List database schemas"`
+	Tables   TablesCmd   `cmd:"" help:"List tables"`
+	SqlInfo  SqlInfoCmd  `cmd:"" name:"sqlinfo" help:"Print the server capabilities (SQL keywords, transaction support, identifier quoting, ...)"`
+	TypeInfo TypeInfoCmd `cmd:"" name:"typeinfo" help:"Print how the server maps SQL types to Arrow types"`
+	Keys     KeysCmd     `cmd:"" help:"Inspect primary and foreign keys"`
+
+	SchemaWatch SchemaWatchCmd `cmd:"" help:"Periodically fetch the schemas of tables and report when they change"`
+
+	Bench BenchCmd `cmd:"" help:"Run a query repeatedly and report timing statistics"`
+	Queue QueueCmd `cmd:"" help:"Run the queries listed in a file with a pool of concurrent workers"`
+	Sweep SweepCmd `cmd:"" help:"Run a query against many databases, concatenating the results with a database column"`
+	Copy  CopyCmd  `cmd:"" help:"Stream the results of a query into a table, possibly on another server"`
+
+	Verify VerifyCmd `cmd:"" help:"Check the detached signature of an export written with query --sign"`
+
+	Conformance ConformanceCmd `cmd:"" help:"Exercise the Flight SQL surface of the server and report a pass/fail matrix"`
+	Doctor      DoctorCmd      `cmd:"" help:"Diagnose connection problems step by step"`
 
 	Version kong.VersionFlag `name:"version" help:"Print version information and quit"`
 }
 
 type QueryCmd struct {
-	Query      string   `arg:"" help:"Query text"`
-	SkipWarmup bool     `optional:"" help:"Skip warmup request"`
-	Output     *os.File `short:"o" optional:"" help:"filename where output is printed"`
+	Query        string        `arg:"" optional:"" help:"Query text, or - to read it from stdin"`
+	File         []string      `short:"f" type:"existingfile" sep:"none" placeholder:"FILE" help:"Read the query text from FILE (repeatable)"`
+	SkipWarmup   bool          `optional:"" help:"Skip warmup request (same as --warmup=never)"`
+	Timeout      time.Duration `help:"Abort the query if executing and fetching it takes longer than this, cancelling it on the server"`
+	Output       string        `short:"o" type:"path" optional:"" help:"filename where output is printed"`
+	Format       string        `enum:",table,json,ndjson,csv,tsv,parquet,arrow,xlsx" default:"" help:"Output format: table, json, ndjson, csv, tsv, parquet, arrow or xlsx (default: inferred from the -o extension, otherwise table)"`
+	NoHeader     bool          `help:"Omit the header line of the csv and tsv formats"`
+	CSVDelimiter string        `name:"csv-delimiter" placeholder:"CHAR" help:"Field delimiter of the csv and tsv formats (e.g. ';', or \\t for a tab)"`
+	CSVQuote     string        `name:"csv-quote" default:"\"" placeholder:"CHAR" help:"Quote character of the csv and tsv formats"`
+	DecimalComma bool          `help:"Write floats and decimals with a decimal comma in the csv and tsv formats, for European locales (best with --csv-delimiter ';')"`
+	Sparkline    []string      `placeholder:"COLUMN,..." help:"After the results, print the values of these numeric columns as unicode sparklines"`
+	Hist         []string      `sep:"none" placeholder:"COLUMN[:BUCKETS]" help:"After the results, print a histogram of the values of COLUMN, in BUCKETS equal-width buckets (default 10) if numeric, otherwise the most frequent values"`
+	Timeline     string        `placeholder:"COLUMN:INTERVAL" help:"After the results, chart the number of rows per INTERVAL (e.g. 5m, 1h, 1d) of the time COLUMN, showing gaps"`
+	SubtotalBy   string        `placeholder:"COLUMN" help:"In table output, follow each group of consecutive rows with the same COLUMN value by a row summing the numeric columns, and end with a grand total"`
+	Pivot        bool          `help:"Swap rows and columns, printing one line per column (for small results with many columns)"`
+	Expanded     bool          `short:"x" help:"Print each row vertically as column | value lines, instead of a table (like psql's \\x)"`
+
+	StableOutput bool     `help:"Sort rows and normalize float formatting so that output is identical across runs"`
+	SortKey      []string `help:"Columns to sort by with --stable-output (default: all columns)"`
+
+	Join string `type:"existingfile" placeholder:"FILE" help:"Extend the rows with the columns of the matching row of a local CSV, JSON or Parquet lookup table (a left join on --on)"`
+	On   string `placeholder:"COLUMN[=FILE_COLUMN]" help:"Key column of --join, named the same in the results and the file, or differently as COLUMN=FILE_COLUMN"`
+
+	Grep string `placeholder:"PATTERN[:COLUMN]" help:"Only print the rows where COLUMN (or any column) matches the PATTERN regular expression"`
+
+	Distinct       bool `help:"Drop duplicate rows client-side, as they stream in"`
+	DistinctMemory int  `placeholder:"N" help:"With --distinct, only remember the last N distinct rows, bounding memory on huge results but letting through duplicates farther apart"`
+
+	MaxRows int64 `placeholder:"N" help:"Stop fetching after N rows, cancelling the rest of the query"`
+
+	Redact []string `sep:"none" placeholder:"COLUMN,...[:hash|mask]" help:"Replace the values of these columns in the output with **** (mask, the default) or a truncated SHA-256 (hash), e.g. to share results containing PII"`
+
+	TableBufferRows int `default:"100000" placeholder:"N" help:"Align the table columns over the first N rows only, then stream the remaining rows to keep memory bounded (0 buffers all the rows)"`
+
+	ShowNullCounts bool `help:"Show the number of NULL values of each column in the table footer"`
+	ShowTypes      bool `help:"Show the Arrow type of each column in a second table header row"`
+	BatchStats     bool `help:"Report the distribution of the size (rows and bytes) of the record batches received"`
+
+	ExpectSchema string `type:"existingfile" placeholder:"FILE" help:"Fail before fetching any data if the result schema differs from the one stored in FILE"`
+	SaveSchema   string `type:"path" placeholder:"FILE" help:"Save the result schema to FILE, for use with --expect-schema"`
+
+	DurationFormat string  `enum:"human,seconds,nanos" default:"human" help:"How duration columns are rendered: human (e.g. 1m30s), seconds or nanos"`
+	BoolFormat     string  `enum:"t/f,true/false,1/0" default:"t/f" help:"How booleans are rendered: t/f, true/false or 1/0 (JSON output always uses true/false)"`
+	TZ             string  `name:"tz" placeholder:"ZONE" help:"Time zone timestamps are shown in: local, UTC or a name like Europe/Rome (default: the zone of the column type, if any, otherwise UTC)"`
+	ShowTZOffset   bool    `name:"show-tz-offset" help:"Append the UTC offset to timestamps (e.g. +02:00)"`
+	NullString     *string `placeholder:"STRING" help:"How NULLs are rendered (default: NULL, or an empty field in csv and tsv; JSON output always uses null)"`
+	BinaryFormat   string  `enum:",hex,base64,escape" default:"" help:"How binary values are rendered: hex (\\x0102, like psql), base64 or escape (like PostgreSQL's bytea escape format)"`
+
+	PlanFormat string `enum:",dot,mermaid" default:"" help:"Render the result of an EXPLAIN query as a Graphviz (dot) or Mermaid diagram"`
+
+	MaxBytes byteSize `placeholder:"SIZE" help:"Abort once more than this much data (e.g. 100MB) has been received, asking whether to continue on a terminal"`
+
+	PageSize int `help:"Fetch results a page at a time by wrapping the query with LIMIT/OFFSET, with next/prev navigation on a terminal"`
+	Page     int `default:"1" help:"First page shown with --page-size"`
+
+	ChunkRows      int    `placeholder:"N" help:"Export the results to -o in files of N rows each (out-000001.csv, ...), fetched with LIMIT/OFFSET, listed in a resumable manifest (out.csv.manifest.json)"`
+	ResumeManifest string `type:"existingfile" placeholder:"FILE" help:"Continue the interrupted chunked export described by the manifest FILE"`
+
+	Params       []queryParam `name:"param" sep:"none" placeholder:"NAME=VALUE" help:"Bind a parameter ($1, $2, ... in order) and run the query as a prepared statement"`
+	ParamsFile   string       `type:"existingfile" placeholder:"FILE" help:"Run the query as a prepared statement once for each row of parameters in a CSV (with header) or JSON file, reporting per-row status and timings"`
+	ParamsBatch  bool         `help:"With --params-file, bind all the rows at once and execute the statement a single time"`
+	ParamsSchema string       `type:"existingfile" placeholder:"FILE" help:"JSON file giving the type, null markers and time format of each --params-file column, instead of inferring them"`
+
+	Project []string `placeholder:"COLUMN,..." help:"Fetch only these columns, pushing the projection to the server by rewriting the query or dropping the other columns client-side if that fails"`
+
+	ArtifactsDir string `placeholder:"DIR" help:"Save the query, timings, schema and trace IDs of this run under a unique run ID in DIR"`
+	ArtifactsRaw bool   `help:"Also save the raw results as an Arrow IPC stream in the artifacts directory"`
+
+	Sign string `type:"existingfile" placeholder:"KEY" help:"Sign the -o export with the PEM private key KEY (ed25519, ECDSA or RSA), writing a detached signature next to it (out.csv.sig)"`
+
+	Verify int `placeholder:"N" help:"Execute the query N times and check that all runs return identical results, ignoring row order"`
+
+	ContinueOnError bool `help:"When running several ;-separated statements, run the remaining ones after a failure"`
+	Jobs            int  `default:"1" placeholder:"N" help:"When running several ;-separated statements, run up to N at a time, buffering the output of each to print it in the order of the statements"`
+
+	TimingsFormat string `enum:"text,json" default:"text" help:"Format of the timings summary: text, or json for CI benchmarks (durations in seconds, rows and bytes received)"`
+	TimingsFile   string `type:"path" placeholder:"FILE" help:"Write the timings summary to FILE instead of stdout"`
+
+	Materialize       string `placeholder:"TABLE" help:"Also insert the results into TABLE, with prepared INSERTs sent via DoPut (for servers lacking CREATE TABLE AS)"`
+	MaterializeCreate bool   `help:"Create the --materialize table first, with the columns of the results"`
+	MaterializeURL    string `name:"materialize-url" placeholder:"URL" help:"Server of the --materialize table, if not the one queried (with the same credentials)"`
+	MaterializeDB     string `name:"materialize-db" placeholder:"DB" help:"Database of the --materialize table, if not the one queried"`
+
+	RetryQuery int `placeholder:"N" help:"On failure, start over up to N times: reconnect, re-execute the query and rewrite the output file from scratch"`
+
+	// statements are the statements of the query, when it has more than one
+	statements []string
+	// counts are the rows and bytes received by the last attempt
+	counts *resultCounter
 }
 
 func (cmd *QueryCmd) Run(cli *Context) error {
-	ctx := metadata.AppendToOutgoingContext(context.Background(),
+	if err := cmd.readQuery(); err != nil {
+		return err
+	}
+	cli.audit.setQuery(cmd.Query)
+	e := hookEvent{Phase: "pre", URL: cli.URL, DB: cli.DB, Query: cmd.Query}
+	if err := runHooks(cli.PreQueryHook, e); err != nil {
+		return err
+	}
+
+	var art *artifacts
+	if cmd.ArtifactsDir != "" {
+		var err error
+		if art, err = newArtifacts(cmd.ArtifactsDir, cmd.ArtifactsRaw); err != nil {
+			return err
+		}
+		if err := art.writeQuery(cmd.Query); err != nil {
+			return err
+		}
+	}
+
+	e.Phase = "post"
+	started := time.Now()
+	e.Timings, e.Err = cmd.runRetrying(cli, art)
+	var rows int64
+	if cmd.counts != nil {
+		rows = cmd.counts.rows
+	}
+	exportMetrics(cli.CLI, started, e.Timings, rows, e.Err)
+	if cli.responses != nil {
+		// also after failures, when the query ID matters the most
+		summary := cmd.summaryWriter()
+		fmt.Fprintln(summary)
+		cli.responses.print(summary)
+	}
+	cli.progress.done(e.Err)
+	if art != nil {
+		if err := art.finish(cli, e.Timings, e.Err); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fmt.Fprintf(os.Stderr, "Artifacts of run %s saved in %s\n", art.RunID, art.Dir)
+	}
+	postHooks := cli.PostQueryHook
+	if cli.Notify {
+		// first, so that a failing hook doesn't prevent the notification
+		postHooks = append([]string{"@notify"}, postHooks...)
+	}
+	err := runHooks(postHooks, e)
+	if e.Err != nil {
+		// the query error is more relevant, but don't hide the hook failure
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return e.Err
+	}
+	return err
+}
+
+// readQuery reads the query text from the -f files or from stdin, if asked to,
+// and splits it into statements.
+func (cmd *QueryCmd) readQuery() error {
+	var texts []string
+	switch {
+	case len(cmd.File) > 0 && cmd.Query != "":
+		return fmt.Errorf("give either the query text or -f, not both")
+	case len(cmd.File) > 0:
+		for _, f := range cmd.File {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				return err
+			}
+			texts = append(texts, string(b))
+		}
+	case cmd.Query == "-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		texts = append(texts, string(b))
+	case cmd.Query == "":
+		return fmt.Errorf("missing query: give the query text, - to read it from stdin, or -f FILE")
+	default:
+		texts = append(texts, cmd.Query)
+	}
+
+	var statements []string
+	for _, text := range texts {
+		statements = append(statements, splitStatements(text)...)
+	}
+	if len(statements) > 1 {
+		cmd.statements = statements
+	}
+	if len(texts) > 1 || cmd.Query == "-" || len(cmd.File) > 0 {
+		cmd.Query = strings.TrimSpace(strings.Join(texts, "\n"))
+	}
+	return nil
+}
+
+// summaryWriter returns where the summaries following the results (timings, charts and statistics) are printed:
+// stderr when the results are written to stdout in a machine-readable format, so that they can be piped to other tools.
+func (cmd *QueryCmd) summaryWriter() io.Writer {
+	if cmd.Output == "" && (cmd.Format != "" && cmd.Format != "table" || cmd.PlanFormat != "") {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// modes returns the flags given that select how the query is run, of which only one can be given.
+func (cmd *QueryCmd) modes(chunked bool) []string {
+	var modes []string
+	for _, m := range []struct {
+		name string
+		set  bool
+	}{
+		{"--plan-format", cmd.PlanFormat != ""},
+		{"--verify", cmd.Verify > 0},
+		{"--chunk-rows", chunked},
+		{"--page-size", cmd.PageSize > 0},
+		{"--params-file", cmd.ParamsFile != ""},
+		{"--param", len(cmd.Params) > 0},
+		{"multiple statements", len(cmd.statements) > 0},
+		{"--project", len(cmd.Project) > 0},
+	} {
+		if m.set {
+			modes = append(modes, m.name)
+		}
+	}
+	return modes
+}
+
+func (cmd *QueryCmd) run(cli *Context, art *artifacts) (Timings, error) {
+	format := cmd.Format
+	if format == "" {
+		format = formatForFile(cmd.Output)
+	}
+	if cmd.SubtotalBy != "" && (cmd.Pivot || format != "" && format != "table") {
+		return Timings{}, fmt.Errorf("--subtotal-by only applies to the table format")
+	}
+	if cmd.Expanded {
+		if format != "" && format != "table" {
+			return Timings{}, fmt.Errorf("--expanded can't be used with the %s format", format)
+		}
+		format = "expanded"
+	}
+	if cmd.Jobs < 1 {
+		return Timings{}, fmt.Errorf("--jobs must be at least 1")
+	}
+	if cmd.ParamsFile != "" && (cmd.Output != "" || cmd.MaxRows > 0 || cmd.Grep != "" || cmd.Distinct) {
+		// the results of each execution are discarded, only reporting its status and timings
+		return Timings{}, fmt.Errorf("--params-file can't be used with -o, --max-rows, --grep or --distinct")
+	}
+	if cmd.PageSize > 0 && cmd.Page < 1 {
+		return Timings{}, fmt.Errorf("--page must be at least 1")
+	}
+	if cmd.RetryQuery > 0 && cmd.Materialize != "" {
+		// the rows inserted by a failed attempt would be inserted again
+		return Timings{}, fmt.Errorf("--retry-query can't be used with --materialize")
+	}
+	if cmd.Materialize != "" && len(cmd.statements) > 0 {
+		// the INSERT is prepared for the columns of the first results
+		return Timings{}, fmt.Errorf("--materialize takes a single statement")
+	}
+	chunked := cmd.ChunkRows > 0 || cmd.ResumeManifest != ""
+	if modes := cmd.modes(chunked); len(modes) > 1 {
+		// each of them runs the query its own way
+		return Timings{}, fmt.Errorf("%s can't be used together", strings.Join(modes, " and "))
+	}
+	if chunked && cmd.Output == "" && cmd.ResumeManifest == "" {
+		return Timings{}, fmt.Errorf("--chunk-rows requires an output file (-o)")
+	}
+	if chunked && (cmd.Grep != "" || cmd.Distinct || cmd.MaxRows > 0) {
+		// a chunk is the last one when it has fewer rows than asked for, which filtered rows would fake
+		return Timings{}, fmt.Errorf("--chunk-rows can't be used with --grep, --distinct or --max-rows")
+	}
+	if binaryFormats[format] && cmd.Output == "" && !chunked {
+		return Timings{}, fmt.Errorf("%s output requires an output file (-o)", format)
+	}
+	var (
+		signer crypto.Signer
+		digest *resultDigest
+	)
+	if cmd.Sign != "" {
+		if cmd.Output == "" || chunked {
+			return Timings{}, fmt.Errorf("--sign requires an output file (-o), and doesn't support chunked exports")
+		}
+		var err error
+		if signer, err = loadSigner(cmd.Sign); err != nil {
+			return Timings{}, err
+		}
+	}
+
+	w := os.Stdout
+	if cmd.Output != "" && !chunked {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return Timings{}, err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	ctx, err := cli.newContext()
+	if err != nil {
+		return Timings{}, err
+	}
+	defer closeReferrals(ctx)
+	var batches *batchStats
+	if cmd.BatchStats {
+		batches = &batchStats{}
+		ctx = withBatchStats(ctx, batches)
+	}
+	var encoding *ipcStats
+	if cli.Verbose {
+		encoding = &ipcStats{}
+		ctx = withIPCStats(ctx, encoding)
+	}
+	if art != nil {
+		if err := art.writeTraceIDs(ctx); err != nil {
+			return Timings{}, err
+		}
+	}
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return Timings{}, err
+	}
+	defer c.Close()
+
+	if cmd.SkipWarmup {
+		cli.Warmup = "never"
+	}
+	warmupDuration, err := cli.warmup(ctx, c)
+	if err != nil {
+		return Timings{}, err
+	}
+	if cmd.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cmd.Timeout)
+		defer cancel()
+	}
+
+	var expectSchema []schemaField
+	if cmd.ExpectSchema != "" {
+		if expectSchema, err = loadSchema(cmd.ExpectSchema); err != nil {
+			return Timings{}, err
+		}
+	}
+
+	opts := printOptions{
+		renderOptions: renderOptions{
+			NormalizeFloats: cmd.StableOutput,
+			DurationFormat:  cmd.DurationFormat,
+			BoolFormat:      cmd.BoolFormat,
+			NullString:      cmd.NullString,
+			BinaryFormat:    cmd.BinaryFormat,
+			ShowTZOffset:    cmd.ShowTZOffset,
+		},
+		Format:          format,
+		NoHeader:        cmd.NoHeader,
+		DecimalComma:    cmd.DecimalComma,
+		StableOutput:    cmd.StableOutput,
+		SortKey:         cmd.SortKey,
+		ShowNullCounts:  cmd.ShowNullCounts,
+		TableBufferRows: cmd.TableBufferRows,
+		Pivot:           cmd.Pivot,
+		SubtotalBy:      cmd.SubtotalBy,
+		MaxRows:         cmd.MaxRows,
+		ShowTypes:       cmd.ShowTypes,
+		ExpectSchema:    expectSchema,
+		SaveSchema:      cmd.SaveSchema,
+		MaxBytes:        cmd.MaxBytes,
+		Distinct:        cmd.Distinct,
+		DistinctMemory:  cmd.DistinctMemory,
+		Summary:         cmd.summaryWriter(),
+	}
+	if cmd.TZ != "" {
+		if opts.TimeZone, err = loadTimeZone(cmd.TZ); err != nil {
+			return Timings{}, err
+		}
+	}
+	if cmd.Join != "" {
+		if cmd.On == "" {
+			return Timings{}, fmt.Errorf("--join requires the key column (--on)")
+		}
+		if opts.Join, err = loadLookupTable(cmd.Join, cmd.On, opts.renderOptions); err != nil {
+			return Timings{}, err
+		}
+	}
+	if opts.CSVDelimiter, err = parseCSVChar("--csv-delimiter", cmd.CSVDelimiter); err != nil {
+		return Timings{}, err
+	}
+	if opts.CSVQuote, err = parseCSVChar("--csv-quote", cmd.CSVQuote); err != nil {
+		return Timings{}, err
+	}
+	if opts.CSVDelimiter != 0 && opts.CSVDelimiter == opts.CSVQuote {
+		return Timings{}, fmt.Errorf("--csv-delimiter and --csv-quote must differ")
+	}
+	if cmd.Grep != "" {
+		if opts.Grep, err = parseGrep(cmd.Grep); err != nil {
+			return Timings{}, err
+		}
+	}
+	if opts.Redact, err = parseRedact(cmd.Redact); err != nil {
+		return Timings{}, err
+	}
+	if art != nil {
+		if len(cmd.statements) > 0 {
+			opts.OnStatementRecord = art.writeStatementRecord
+		} else {
+			opts.OnRecord = art.writeRecord
+		}
+	}
+	var sparks *sparklines
+	if len(cmd.Sparkline) > 0 {
+		sparks = newSparklines(cmd.Sparkline)
+		opts.OnRecord = chainOnRecord(opts.OnRecord, sparks.add)
+	}
+	counts := &resultCounter{}
+	opts.OnRecord = chainOnRecord(opts.OnRecord, counts.add)
+	cli.audit.setCounts(counts)
+	cmd.counts = counts
+	var hists []*histogram
+	for _, arg := range cmd.Hist {
+		h, err := parseHist(arg)
+		if err != nil {
+			return Timings{}, err
+		}
+		hists = append(hists, h)
+		opts.OnRecord = chainOnRecord(opts.OnRecord, h.add)
+	}
+	var tl *timeline
+	if cmd.Timeline != "" {
+		if tl, err = parseTimeline(cmd.Timeline); err != nil {
+			return Timings{}, err
+		}
+		opts.OnRecord = chainOnRecord(opts.OnRecord, tl.add)
+	}
+	if signer != nil {
+		digest = &resultDigest{opts: opts.renderOptions}
+		opts.OnRecord = chainOnRecord(opts.OnRecord, digest.add)
+	}
+	var mat *materializer
+	if cmd.Materialize != "" {
+		mctx, mc, err := cli.materializeClient(ctx, c, cmd.MaterializeURL, cmd.MaterializeDB)
+		if err != nil {
+			return Timings{}, err
+		}
+		if mc != c {
+			defer mc.Close()
+		}
+		mat = newMaterializer(mctx, mc, cmd.Materialize, cmd.MaterializeCreate)
+		defer mat.close()
+		opts.OnRecord = chainOnRecord(opts.OnRecord, mat.add)
+		opts.OnSchema = mat.announce
+	}
+	var timings Timings
+	if cmd.PlanFormat != "" {
+		_, timings, err = printPlan(ctx, w, c, cmd.Query, cmd.PlanFormat)
+	} else if cmd.Verify > 0 {
+		timings, err = verifyQuery(ctx, w, c, cmd.Query, opts.renderOptions, cmd.Verify)
+	} else if chunked {
+		timings, err = runChunkedExport(ctx, c, cmd.Query, cmd.Output, opts, cmd.ChunkRows, cmd.ResumeManifest)
+	} else if cmd.PageSize > 0 {
+		timings, err = printPages(ctx, w, c, cmd.Query, opts, cmd.PageSize, cmd.Page-1)
+	} else if cmd.ParamsFile != "" {
+		timings, err = runParamsFile(ctx, c, cmd.Query, cmd.ParamsFile, cmd.ParamsSchema, cmd.ParamsBatch, opts.OnRecord)
+	} else if len(cmd.Params) > 0 {
+		_, timings, err = printPrepared(ctx, w, c, cmd.Query, cmd.Params, opts)
+	} else if len(cmd.statements) > 0 {
+		timings, err = runStatements(ctx, w, c, cmd.statements, opts, cmd.ContinueOnError, cmd.Jobs)
+	} else if len(cmd.Project) > 0 {
+		_, timings, err = printProjected(ctx, w, c, cmd.Query, cmd.Project, opts)
+	} else {
+		_, timings, err = printQuery(ctx, w, c, cmd.Query, opts)
+	}
+	if err != nil && cmd.Timeout > 0 && ctx.Err() == context.DeadlineExceeded && cli.base.Err() == nil {
+		return Timings{}, fmt.Errorf("query timed out after %s: %w", cmd.Timeout, err)
+	}
+	if err != nil {
+		return Timings{}, err
+	}
+	if cmd.Output != "" && !chunked {
+		if err := w.Close(); err != nil {
+			return Timings{}, err
+		}
+	}
+	if signer != nil {
+		statement := signedStatement{Rows: digest.rows, ResultDigest: digest.String(), Query: cmd.Query, URL: cli.URL, DB: cli.DB}
+		if err := signExport(cmd.Output, signer, statement); err != nil {
+			return Timings{}, fmt.Errorf("signing %s: %w", cmd.Output, err)
+		}
+		fmt.Fprintf(os.Stderr, "Signed %s, signature in %s.sig\n", cmd.Output, cmd.Output)
+	}
+	if mat != nil {
+		rows, err := mat.finish()
+		if err != nil {
+			return Timings{}, err
+		}
+		fmt.Fprintf(os.Stderr, "Materialized %d rows into %s\n", rows, cmd.Materialize)
+	}
+
+	summary := opts.Summary
+	if sparks != nil {
+		fmt.Fprintln(summary)
+		sparks.print(summary)
+	}
+	for _, h := range hists {
+		fmt.Fprintln(summary)
+		h.print(summary)
+	}
+	if tl != nil {
+		fmt.Fprintln(summary)
+		if err := tl.print(summary, opts.renderOptions); err != nil {
+			return Timings{}, err
+		}
+	}
+
+	timings.Add(Timings{Warmup: warmupDuration})
+	if cmd.TimingsFile != "" {
+		f, err := os.Create(cmd.TimingsFile)
+		if err != nil {
+			return Timings{}, err
+		}
+		err = writeTimings(f, cmd.TimingsFormat, timings, counts)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return Timings{}, err
+		}
+	} else {
+		fmt.Fprintln(summary)
+		if err := writeTimings(summary, cmd.TimingsFormat, timings, counts); err != nil {
+			return Timings{}, err
+		}
+	}
+	if cli.wire != nil {
+		fmt.Fprint(summary, cli.wire)
+	}
+	if batches != nil {
+		batches.print(summary)
+	}
+	if cli.Verbose {
+		fp, err := fetchFingerprint(ctx, c)
+		fp.print(summary)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Cannot get the server name and version: %v\n", err)
+		}
+		encoding.print(summary)
+	}
+
+	return timings, nil
+}
+
+// newContext returns a context carrying the request metadata (database, auth, custom and trace headers).
+func (cli *CLI) newContext() (context.Context, error) {
+	if err := cli.runCredentialHelper(); err != nil {
+		return nil, err
+	}
+	if err := cli.exchangeToken(); err != nil {
+		return nil, err
+	}
+	if cli.OAuthTokenURL != "" {
+		if cli.oauth == nil {
+			cli.oauth = &oauthToken{}
+		}
+		// fail early rather than on the first call
+		if _, err := cli.oauthAccessToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	base := cli.base
+	if base == nil {
+		base = context.Background()
+	}
+	ctx := metadata.AppendToOutgoingContext(base,
 		"database", cli.DB,
 		// we need to pass this explicitly because IOx doesn't support the `auth-token` header that flight passes
-		"authorization", "Token "+cli.Token,
+		"authorization", "Token "+cli.accessToken(),
 		// enables special queries
 		"iox-debug", "true",
 	)
 	ctx = metadata.AppendToOutgoingContext(ctx, cli.customHeaders()...)
 
-	if cli.GenTraceId {
-		traceID := generateRandomHex(8)
-		traceHeader := fmt.Sprintf("%s:1112223334445:0:1", traceID)
-		ctx = metadata.AppendToOutgoingContext(ctx,
-			traceIDHeader, traceHeader,
-			traceIDHeader2, traceHeader,
-		)
+	addr, _, err := parseAddr(cli.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	ctx = withReferrals(ctx, addr, cli)
+	if cli.phases != nil {
+		ctx = withPhases(ctx, cli.phases)
+	}
+	if cli.DumpFlightData != "" {
+		if cli.dump == nil {
+			if cli.dump, err = newFlightDataDump(cli.DumpFlightData); err != nil {
+				return nil, err
+			}
+		}
+		ctx = withFlightDataDump(ctx, cli.dump)
+	}
+	if cli.ProgressJSON > 0 {
+		if cli.progress == nil {
+			cli.progress = newProgressWriter(cli.ProgressJSON)
+		}
+		ctx = withProgress(ctx, cli.progress)
+	}
+
+	if headers, traceID := cli.traceHeaders(); headers != nil {
+		ctx = metadata.AppendToOutgoingContext(ctx, headers...)
 
 		fmt.Fprintf(os.Stderr, "Trace ID set to %s\n", traceID)
 	}
+	return ctx, nil
+}
 
-	addr, cred, err := parseAddr(cli.URL)
+// dial creates a new Flight SQL client connected to the server at cli.URL.
+func (cli *CLI) dial(ctx context.Context) (*flightsql.Client, error) {
+	phasesFrom(ctx).enter("connect")
+	tlsConfig, err := cli.tlsConfig()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	c, err := flightsql.NewClientCtx(ctx, addr, cli, nil, grpc.WithTransportCredentials(cred))
+	addr, cred, err := parseAddr(cli.URL, tlsConfig)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// Some time is spend on the first flight request, whatever that request is, let's run a dummy request first
-	// so that we can better measure the other ones
-	beforeWarmup := time.Now()
-	if !cmd.SkipWarmup {
-		if _, err := c.GetCatalogs(ctx); err != nil {
-			return err
+	middleware := []flight.ClientMiddleware{flight.CreateClientMiddleware(cli)}
+	opts := append(cli.dialOptions(), grpc.WithTransportCredentials(cred))
+	c, err := flightsql.NewClientCtx(ctx, addr, cli, middleware, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if cli.Username != "" {
+		if err := cli.handshake(ctx, c); err != nil {
+			c.Close()
+			return nil, err
 		}
 	}
-	warmupDuration := time.Since(beforeWarmup)
+	progressFrom(ctx).emit(progressEvent{Event: "connected"})
+	return c, nil
+}
 
-	w := os.Stdout
-	if cmd.Output != nil {
-		w = cmd.Output
+// dialOptions returns the gRPC options shared by all the connections, to the server and to endpoint locations.
+func (cli *CLI) dialOptions() []grpc.DialOption {
+	var opts []grpc.DialOption
+	if cli.MaxRecvMsgSize > 0 {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(int(cli.MaxRecvMsgSize))))
 	}
-	timings, err := printQuery(ctx, w, c, cmd.Query)
-	if err != nil {
-		return err
+	if cli.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cli.KeepaliveTime,
+			Timeout:             cli.KeepaliveTimeout,
+			PermitWithoutStream: cli.KeepalivePermitWithoutStream,
+		}))
 	}
-
-	fmt.Println()
-	fmt.Print(timings.Add(Timings{Warmup: warmupDuration}))
-
-	return nil
+	if cli.Compression != "none" && cli.wire == nil {
+		cli.wire = &wireStats{}
+	}
+	if cli.DebugGRPC {
+		if cli.grpcLog == nil {
+			cli.grpcLog = newGRPCDebugLog(os.Stderr)
+		}
+		opts = append(opts, grpc.WithStatsHandler(cli.grpcLog))
+	}
+	if cli.ShowMetadata {
+		if cli.responses == nil {
+			cli.responses = &responseMetadata{}
+		}
+		opts = append(opts, grpc.WithStatsHandler(cli.responses))
+	}
+	return append(opts, compressionOptions(cli.Compression, cli.wire)...)
 }
 
 func (cli *CLI) customHeaders() (pairs []string) {
@@ -117,18 +823,25 @@ func (cli *CLI) customHeaders() (pairs []string) {
 	return pairs
 }
 
-func (cli *CLI) Authenticate(context.Context, flight.AuthConn) error {
-	return fmt.Errorf("not implemented")
-}
-func (cli *CLI) GetToken(context.Context) (string, error) {
-	return cli.Token, nil
-}
-
-func parseAddr(s string) (string, credentials.TransportCredentials, error) {
+// parseAddr parses the server URL into an address and transport credentials. https URLs use tlsConfig,
+// or the default TLS configuration if nil. unix:///path/to.sock (or grpc+unix) URLs connect in plaintext
+// to a Unix domain socket, with a unix:/path/to.sock address.
+func parseAddr(s string, tlsConfig *tls.Config) (string, credentials.TransportCredentials, error) {
 	u, err := url.Parse(s)
 	if err != nil {
 		return "", nil, err
 	}
+	if u.Scheme == "unix" || u.Scheme == "grpc+unix" {
+		path := u.Path
+		if u.Opaque != "" {
+			// unix:relative/path.sock
+			path = u.Opaque
+		}
+		if u.Host != "" || path == "" {
+			return "", nil, fmt.Errorf("invalid socket URL %q, expecting %s:///path/to.sock", s, u.Scheme)
+		}
+		return "unix:" + path, insecure.NewCredentials(), nil
+	}
 	p := u.Port()
 	if p == "" {
 		if u.Scheme == "https" {
@@ -142,7 +855,10 @@ func parseAddr(s string) (string, credentials.TransportCredentials, error) {
 	case "http":
 		return a, insecure.NewCredentials(), nil
 	case "https":
-		return a, credentials.NewTLS(&tls.Config{}), nil
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		return a, credentials.NewTLS(tlsConfig), nil
 	default:
 		return "", nil, fmt.Errorf("unhandled schema %q", u.Scheme)
 	}
@@ -167,6 +883,12 @@ func getVersion() string {
 }
 
 func main() {
+	cfg, err := loadConfig(defaultConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "flightclub: error: %v\n", err)
+		os.Exit(1)
+	}
+
 	var cli CLI
 	ctx := kong.Parse(&cli,
 		kong.UsageOnError(),
@@ -177,7 +899,41 @@ func main() {
 			Compact: true,
 			Summary: true,
 		}),
+		kong.Bind(cfg),
+		kong.Resolvers(profileResolver(cfg)),
 	)
-	err := ctx.Run(&Context{CLI: &cli})
+	ctx.FatalIfErrorf(cli.applyDSN())
+	cli.Copy.applySource(&cli)
+	ctx.FatalIfErrorf(cli.checkTarget())
+	cli.locations = cfg.Locations
+	cli.sticky = cli.newStickyHeaders()
+	if cfg.AuditLog != "" {
+		cli.audit, err = openAuditLog(cfg.AuditLog, ctx.Command())
+		ctx.FatalIfErrorf(err, "audit log")
+	}
+	base := context.Background()
+	if cli.Deadline > 0 {
+		var cancel context.CancelFunc
+		base, cancel = context.WithTimeout(base, cli.Deadline)
+		defer cancel()
+		cli.phases = &phaseTracker{}
+	}
+	var stop context.CancelFunc
+	cli.base, stop = interruptible(base)
+	defer stop()
+	err = ctx.Run(&Context{CLI: &cli})
+	if err != nil && cli.Deadline > 0 && cli.base.Err() == context.DeadlineExceeded {
+		cli.phases.report(os.Stderr, cli.Deadline)
+	}
+	if auditErr := cli.audit.write(&cli, err); auditErr != nil {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "flightclub: audit log: %v\n", auditErr)
+		} else {
+			err = fmt.Errorf("audit log: %w", auditErr)
+		}
+	}
+	if err != nil {
+		err = withHint(err)
+	}
 	ctx.FatalIfErrorf(err)
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// materializer writes the results back to a table (--materialize), as they stream in: each record is bound
+// as the parameters of a prepared INSERT, sent with DoPut, so it works even on servers lacking CREATE TABLE AS.
+type materializer struct {
+	ctx    context.Context
+	c      *flightsql.Client
+	table  string
+	create bool
+
+	// schema is the one announced by the server, to prepare the INSERT (and create the table) even if no records arrive
+	schema *arrow.Schema
+
+	stmt *flightsql.PreparedStatement
+	rows int64
+}
+
+func newMaterializer(ctx context.Context, c *flightsql.Client, table string, create bool) *materializer {
+	return &materializer{ctx: ctx, c: c, table: table, create: create}
+}
+
+func (m *materializer) add(record arrow.Record) error {
+	if m.stmt == nil {
+		if err := m.prepare(record.Schema()); err != nil {
+			return err
+		}
+	}
+	if record.NumRows() == 0 {
+		return nil
+	}
+	m.stmt.SetParameters(record)
+	if _, err := m.stmt.ExecuteUpdate(m.ctx); err != nil {
+		return fmt.Errorf("inserting into %s: %w", m.table, err)
+	}
+	m.rows += record.NumRows()
+	return nil
+}
+
+// prepare creates the table, if asked to, and prepares the INSERT of the results.
+func (m *materializer) prepare(schema *arrow.Schema) error {
+	var names, placeholders []string
+	for _, f := range schema.Fields() {
+		names = append(names, quoteIdent(f.Name))
+		placeholders = append(placeholders, "?")
+	}
+	if m.create {
+		ddl, err := createTableSQL(m.table, schema)
+		if err != nil {
+			return err
+		}
+		if _, err := m.c.ExecuteUpdate(m.ctx, ddl); err != nil {
+			return fmt.Errorf("creating %s: %w", m.table, err)
+		}
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", m.table, strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	stmt, err := m.c.Prepare(m.ctx, insert)
+	if err != nil {
+		return fmt.Errorf("preparing the insert into %s: %w", m.table, err)
+	}
+	m.stmt = stmt
+	return nil
+}
+
+// announce records the schema announced by the server.
+func (m *materializer) announce(schema *arrow.Schema) {
+	m.schema = schema
+}
+
+// finish prepares the INSERT from the announced schema if no records arrived, so that an empty result still
+// creates the table, then closes the materializer.
+func (m *materializer) finish() (int64, error) {
+	if m.stmt == nil {
+		if m.schema != nil {
+			if err := m.prepare(m.schema); err != nil {
+				return 0, err
+			}
+		} else if m.create {
+			return 0, fmt.Errorf("cannot create %s: no records received, and the server didn't announce their schema", m.table)
+		}
+	}
+	return m.close()
+}
+
+// close releases the prepared statement, returning the number of rows written.
+// Closing again does nothing, so that it can also be deferred for the error paths.
+func (m *materializer) close() (int64, error) {
+	stmt := m.stmt
+	if stmt == nil {
+		return m.rows, nil
+	}
+	m.stmt = nil
+	return m.rows, stmt.Close(m.ctx)
+}
+
+// materializeClient returns the client of the server of the --materialize table, and the context of its calls:
+// c and ctx, unless another URL or database is given.
+func (cli *CLI) materializeClient(ctx context.Context, c *flightsql.Client, url, db string) (context.Context, *flightsql.Client, error) {
+	if url == "" && db == "" {
+		return ctx, c, nil
+	}
+	target := *cli
+	if url != "" {
+		target.URL = url
+		target.sticky = target.newStickyHeaders()
+	}
+	if db != "" {
+		target.DB = db
+	}
+	ctx, err := target.newContext()
+	if err != nil {
+		return nil, nil, err
+	}
+	mc, err := target.dial(ctx)
+	return ctx, mc, err
+}
+
+// createTableSQL returns the CREATE TABLE statement of a table with the columns of schema, in standard SQL types.
+func createTableSQL(table string, schema *arrow.Schema) (string, error) {
+	var columns []string
+	for _, f := range schema.Fields() {
+		t, err := sqlType(f.Type)
+		if err != nil {
+			return "", fmt.Errorf("column %q: %w", f.Name, err)
+		}
+		columns = append(columns, quoteIdent(f.Name)+" "+t)
+	}
+	return fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columns, ", ")), nil
+}
+
+func sqlType(dt arrow.DataType) (string, error) {
+	switch dt := dt.(type) {
+	case *arrow.Decimal128Type:
+		return fmt.Sprintf("DECIMAL(%d, %d)", dt.Precision, dt.Scale), nil
+	case *arrow.Decimal256Type:
+		return fmt.Sprintf("DECIMAL(%d, %d)", dt.Precision, dt.Scale), nil
+	case *arrow.DictionaryType:
+		return sqlType(dt.ValueType)
+	}
+	switch dt.ID() {
+	case arrow.BOOL:
+		return "BOOLEAN", nil
+	case arrow.INT8, arrow.INT16, arrow.UINT8:
+		return "SMALLINT", nil
+	case arrow.INT32, arrow.UINT16:
+		return "INTEGER", nil
+	case arrow.INT64, arrow.UINT32, arrow.UINT64:
+		return "BIGINT", nil
+	case arrow.FLOAT16, arrow.FLOAT32:
+		return "REAL", nil
+	case arrow.FLOAT64:
+		return "DOUBLE PRECISION", nil
+	case arrow.STRING, arrow.LARGE_STRING:
+		return "VARCHAR", nil
+	case arrow.BINARY, arrow.LARGE_BINARY, arrow.FIXED_SIZE_BINARY:
+		return "VARBINARY", nil
+	case arrow.DATE32, arrow.DATE64:
+		return "DATE", nil
+	case arrow.TIME32, arrow.TIME64:
+		return "TIME", nil
+	case arrow.TIMESTAMP:
+		return "TIMESTAMP", nil
+	}
+	return "", fmt.Errorf("no SQL type for %s, create the table beforehand", dt)
+}
+
+// quoteIdent quotes a column name as a SQL identifier.
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	pb "github.com/apache/arrow/go/v15/arrow/flight/gen/flight"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// CatalogsCmd lists the catalogs of the server.
+type CatalogsCmd struct{}
+
+func (cmd *CatalogsCmd) Run(cli *Context) error {
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetCatalogs(ctx)
+	}, printOptions{})
+}
+
+// SchemasCmd lists the database schemas of the server.
+type SchemasCmd struct {
+	Catalog       string `help:"Only list the schemas of this catalog"`
+	SchemaPattern string `help:"Only list the schemas matching this pattern (% and _ wildcards)"`
+}
+
+func (cmd *SchemasCmd) Run(cli *Context) error {
+	opts := &flightsql.GetDBSchemasOpts{
+		Catalog:               optionalString(cmd.Catalog),
+		DbSchemaFilterPattern: optionalString(cmd.SchemaPattern),
+	}
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetDBSchemas(ctx, opts)
+	}, printOptions{})
+}
+
+// TablesCmd lists the tables of the server.
+type TablesCmd struct {
+	Catalog       string   `help:"Only list the tables of this catalog"`
+	SchemaPattern string   `help:"Only list the tables of the schemas matching this pattern (% and _ wildcards)"`
+	TablePattern  string   `help:"Only list the tables matching this pattern (% and _ wildcards)"`
+	TableType     []string `help:"Only list tables of these types (e.g. TABLE, VIEW)"`
+	IncludeSchema bool     `help:"Include the schema of each table"`
+}
+
+func (cmd *TablesCmd) Run(cli *Context) error {
+	opts := &flightsql.GetTablesOpts{
+		Catalog:                optionalString(cmd.Catalog),
+		DbSchemaFilterPattern:  optionalString(cmd.SchemaPattern),
+		TableNameFilterPattern: optionalString(cmd.TablePattern),
+		TableTypes:             cmd.TableType,
+		IncludeSchema:          cmd.IncludeSchema,
+	}
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetTables(ctx, opts)
+	}, printOptions{Transform: decodeTableSchemas})
+}
+
+// SqlInfoCmd prints the capabilities of the server.
+type SqlInfoCmd struct {
+	Info []string `arg:"" optional:"" help:"Only print these infos, by name (e.g. FLIGHT_SQL_SERVER_NAME) or numeric code"`
+}
+
+func (cmd *SqlInfoCmd) Run(cli *Context) error {
+	var infos []flightsql.SqlInfo
+	for _, name := range cmd.Info {
+		code, ok := pb.SqlInfo_value[strings.ToUpper(name)]
+		if !ok {
+			n, err := strconv.ParseUint(name, 10, 32)
+			if err != nil {
+				return fmt.Errorf("unknown SQL info %q", name)
+			}
+			code = int32(n)
+		}
+		infos = append(infos, flightsql.SqlInfo(code))
+	}
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetSqlInfo(ctx, infos)
+	}, printOptions{Transform: decodeSqlInfo})
+}
+
+// TypeInfoCmd prints how the server maps SQL types to Arrow types.
+type TypeInfoCmd struct {
+	DataType *int32 `arg:"" optional:"" help:"Only print the types with this XDBC data type code (e.g. 12 for VARCHAR)"`
+}
+
+func (cmd *TypeInfoCmd) Run(cli *Context) error {
+	return cli.printMetadata(func(ctx context.Context, c *flightsql.Client) (*flight.FlightInfo, error) {
+		return c.GetXdbcTypeInfo(ctx, cmd.DataType)
+	}, printOptions{})
+}
+
+// printMetadata prints the result of a metadata request.
+func (cli *Context) printMetadata(request func(context.Context, *flightsql.Client) (*flight.FlightInfo, error), opts printOptions) error {
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	replan := func(ctx context.Context) (*flight.FlightInfo, error) { return request(ctx, c) }
+	info, err := replan(ctx)
+	if err != nil {
+		return err
+	}
+	_, _, err = printInfo(ctx, os.Stdout, c, info, replan, opts)
+	return err
+}
+
+// optionalString returns nil for an empty string, i.e. a filter that wasn't given.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// decodeTableSchemas replaces the table_schema column of a GetTables result, which holds
+// IPC serialized schemas, with a readable list of columns and types.
+func decodeTableSchemas(record arrow.Record) (arrow.Record, error) {
+	col := columnIndex(getHeader(record), "table_schema")
+	if col < 0 {
+		record.Retain()
+		return record, nil
+	}
+	schemas, ok := record.Column(col).(*array.Binary)
+	if !ok {
+		record.Retain()
+		return record, nil
+	}
+
+	b := array.NewStringBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	for r := 0; r < schemas.Len(); r++ {
+		if schemas.IsNull(r) {
+			b.AppendNull()
+			continue
+		}
+		schema, err := flight.DeserializeSchema(schemas.Value(r), memory.DefaultAllocator)
+		if err != nil {
+			return nil, err
+		}
+		var fields []string
+		for _, f := range describeSchema(schema) {
+			fields = append(fields, f.String())
+		}
+		b.Append(strings.Join(fields, ", "))
+	}
+	decoded := b.NewArray()
+	defer decoded.Release()
+
+	fields := record.Schema().Fields()
+	fields[col] = arrow.Field{Name: fields[col].Name, Type: arrow.BinaryTypes.String, Nullable: fields[col].Nullable}
+	cols := record.Columns()
+	cols = append(cols[:col:col], append([]arrow.Array{decoded}, cols[col+1:]...)...)
+	metadata := record.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &metadata), cols, record.NumRows()), nil
+}
+
+// decodeSqlInfo turns a GetSqlInfo result, made of numeric codes and union values, into readable names and values.
+func decodeSqlInfo(record arrow.Record) (arrow.Record, error) {
+	header := getHeader(record)
+	nameCol, valueCol := columnIndex(header, "info_name"), columnIndex(header, "value")
+	if nameCol < 0 || valueCol < 0 {
+		record.Retain()
+		return record, nil
+	}
+	codes, ok := record.Column(nameCol).(*array.Uint32)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type of info_name: %s", record.Column(nameCol).DataType())
+	}
+	values, ok := record.Column(valueCol).(*array.DenseUnion)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type of value: %s", record.Column(valueCol).DataType())
+	}
+
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "info_name", Type: arrow.BinaryTypes.String},
+		{Name: "value", Type: arrow.BinaryTypes.String, Nullable: true},
+	}, nil)
+	b := array.NewRecordBuilder(memory.DefaultAllocator, schema)
+	defer b.Release()
+	names, strs := b.Field(0).(*array.StringBuilder), b.Field(1).(*array.StringBuilder)
+	for r := 0; r < int(record.NumRows()); r++ {
+		names.Append(flightsql.SqlInfo(codes.Value(r)).String())
+		child := values.Field(values.ChildID(r))
+		offset := int(values.ValueOffset(r))
+		if child.IsNull(offset) {
+			strs.AppendNull()
+		} else {
+			strs.Append(child.ValueStr(offset))
+		}
+	}
+	return b.NewRecord(), nil
+}
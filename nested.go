@@ -0,0 +1,99 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+)
+
+// renderNested renders a list, map or struct value with a JSON-like syntax, e.g. [1, 2, null],
+// {"host": "a", "cpu": 0.5} or {"k1": [1], "k2": []}. The elements are rendered like top-level values,
+// and quoted unless they are numbers, booleans or nested values themselves.
+func (o renderOptions) renderNested(column arrow.Array, row int) (string, error) {
+	var sb strings.Builder
+	switch typedColumn := column.(type) {
+	case *array.Map:
+		start, end := typedColumn.ValueOffsets(row)
+		sb.WriteByte('{')
+		for i := int(start); i < int(end); i++ {
+			if i > int(start) {
+				sb.WriteString(", ")
+			}
+			if err := o.writeElement(&sb, typedColumn.Keys(), i, true); err != nil {
+				return "", err
+			}
+			sb.WriteString(": ")
+			if err := o.writeElement(&sb, typedColumn.Items(), i, false); err != nil {
+				return "", err
+			}
+		}
+		sb.WriteByte('}')
+	case array.ListLike:
+		start, end := typedColumn.ValueOffsets(row)
+		sb.WriteByte('[')
+		for i := int(start); i < int(end); i++ {
+			if i > int(start) {
+				sb.WriteString(", ")
+			}
+			if err := o.writeElement(&sb, typedColumn.ListValues(), i, false); err != nil {
+				return "", err
+			}
+		}
+		sb.WriteByte(']')
+	case *array.Struct:
+		st := typedColumn.DataType().(*arrow.StructType)
+		sb.WriteByte('{')
+		for f := 0; f < typedColumn.NumField(); f++ {
+			if f > 0 {
+				sb.WriteString(", ")
+			}
+			name, _ := json.Marshal(st.Field(f).Name)
+			sb.Write(name)
+			sb.WriteString(": ")
+			if err := o.writeElement(&sb, typedColumn.Field(f), row, false); err != nil {
+				return "", err
+			}
+		}
+		sb.WriteByte('}')
+	}
+	return sb.String(), nil
+}
+
+// writeElement writes an element of a nested value. Keys are always quoted.
+func (o renderOptions) writeElement(sb *strings.Builder, column arrow.Array, row int, key bool) error {
+	if column.IsNull(row) {
+		sb.WriteString("null")
+		return nil
+	}
+	if b, ok := column.(*array.Boolean); ok && !key {
+		// whatever --bool-format says, like JSON
+		sb.WriteString(strconv.FormatBool(b.Value(row)))
+		return nil
+	}
+	s, err := o.renderText(column, row)
+	if err != nil {
+		return err
+	}
+	if key || !unquotedElement(column.DataType()) {
+		b, _ := json.Marshal(s)
+		s = string(b)
+	}
+	sb.WriteString(s)
+	return nil
+}
+
+// unquotedElement tells whether elements of type dt are written as they are rendered in nested values.
+func unquotedElement(dt arrow.DataType) bool {
+	switch dt := dt.(type) {
+	case *arrow.DictionaryType:
+		return unquotedElement(dt.ValueType)
+	case *arrow.RunEndEncodedType:
+		return unquotedElement(dt.Encoded())
+	case arrow.NestedType:
+		return true
+	}
+	return arrow.IsInteger(dt.ID()) || arrow.IsFloating(dt.ID()) || arrow.IsDecimal(dt.ID()) || dt.ID() == arrow.BOOL
+}
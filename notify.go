@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+)
+
+// notifyDone rings the terminal bell and shows a desktop notification telling whether the query succeeded.
+// Failing to show the notification (e.g. on a headless machine) isn't an error, the bell is enough.
+func notifyDone(e hookEvent) error {
+	title, body := "flightclub: query finished", fmt.Sprintf("%s in %s", e.DB, e.Timings.Total())
+	if e.Err != nil {
+		title, body = "flightclub: query failed", e.Err.Error()
+	}
+	if _, err := fmt.Fprint(os.Stderr, "\a"); err != nil {
+		return err
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("osascript", "-e", "display notification "+strconv.Quote(body)+" with title "+strconv.Quote(title))
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", title, body)
+	default:
+		return nil
+	}
+	cmd.Run()
+	return nil
+}
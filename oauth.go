@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oauthRefreshMargin is how long before its expiry an OAuth access token is refreshed,
+// so that it doesn't expire while a request is in flight.
+const oauthRefreshMargin = 30 * time.Second
+
+// oauthToken caches the access token obtained with the OAuth 2.0 client credentials grant.
+type oauthToken struct {
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// oauthAccessToken returns a valid access token from --oauth-token-url, requesting a new one
+// with the client credentials grant the first time and whenever the current one is about to expire.
+func (cli *CLI) oauthAccessToken() (string, error) {
+	cli.oauth.mu.Lock()
+	defer cli.oauth.mu.Unlock()
+	if cli.oauth.token != "" && (cli.oauth.expiry.IsZero() || time.Now().Add(oauthRefreshMargin).Before(cli.oauth.expiry)) {
+		return cli.oauth.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {cli.OAuthClientID},
+		"client_secret": {cli.OAuthClientSecret},
+	}
+	if len(cli.OAuthScope) > 0 {
+		form.Set("scope", strings.Join(cli.OAuthScope, " "))
+	}
+	r, err := requestToken(cli.OAuthTokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("oauth: %w", err)
+	}
+	cli.oauth.token = r.AccessToken
+	cli.oauth.expiry = time.Time{}
+	if r.ExpiresIn > 0 {
+		cli.oauth.expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	return cli.oauth.token, nil
+}
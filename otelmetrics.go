@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// otlpDurationBounds are the bucket bounds, in seconds, of the duration histograms.
+var otlpDurationBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.075, 0.1, 0.25, 0.5, 0.75, 1, 2.5, 5, 7.5, 10, 30, 60, 300}
+
+// otlpMetricsEndpoint returns the URL metrics are exported to, if OTLP is configured with the standard
+// OTEL_EXPORTER_OTLP_METRICS_ENDPOINT or OTEL_EXPORTER_OTLP_ENDPOINT environment variables.
+func otlpMetricsEndpoint() string {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		return strings.TrimSuffix(endpoint, "/") + "/v1/metrics"
+	}
+	return ""
+}
+
+// exportMetrics sends the timings of a query as OpenTelemetry metrics to the OTLP endpoint, if configured,
+// in the OTLP/HTTP JSON encoding:
+//
+//	flightclub.query.duration  histogram of the duration of each phase (warmup, execute, doget and total), in seconds
+//	flightclub.query.rows      counter of the rows received
+//	flightclub.queries         counter of the queries, by status (ok or error)
+//
+// The data points carry the db, server URL and status as attributes, and the resource is described by
+// OTEL_SERVICE_NAME (default flightclub) and OTEL_RESOURCE_ATTRIBUTES. Exporting is best effort:
+// failures are reported but don't fail the query.
+func exportMetrics(cli *CLI, started time.Time, timings Timings, rows int64, queryErr error) {
+	endpoint := otlpMetricsEndpoint()
+	if endpoint == "" {
+		return
+	}
+	if proto := otelEnv("PROTOCOL"); proto != "" && proto != "http/json" {
+		fmt.Fprintf(os.Stderr, "OTLP protocol %s isn't supported, exporting metrics with http/json\n", proto)
+	}
+	body, err := json.Marshal(otlpMetrics(cli, started, time.Now(), timings, rows, queryErr))
+	if err == nil {
+		err = postOTLP(endpoint, body)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot export metrics to %s: %v\n", endpoint, err)
+	}
+}
+
+// otelEnv returns the metrics-specific OTEL_EXPORTER_OTLP_METRICS_<name> setting, or else OTEL_EXPORTER_OTLP_<name>.
+func otelEnv(name string) string {
+	if v := os.Getenv("OTEL_EXPORTER_OTLP_METRICS_" + name); v != "" {
+		return v
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_" + name)
+}
+
+func postOTLP(endpoint string, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range parseOTELList(otelEnv("HEADERS")) {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// parseOTELList parses the key=value,... lists of the OTEL_* environment variables, whose values are URL-encoded.
+func parseOTELList(s string) map[string]string {
+	m := map[string]string{}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(strings.TrimSpace(v)); err == nil {
+			v = unescaped
+		}
+		m[strings.TrimSpace(k)] = v
+	}
+	return m
+}
+
+// The types below are the subset of the OTLP JSON encoding we need. As in the protobuf JSON mapping,
+// 64-bit integers are strings.
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpDataPoint struct {
+	Attributes        []otlpAttribute `json:"attributes"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+
+	// sums
+	AsInt string `json:"asInt,omitempty"`
+
+	// histograms
+	Count          string    `json:"count,omitempty"`
+	Sum            *float64  `json:"sum,omitempty"`
+	BucketCounts   []string  `json:"bucketCounts,omitempty"`
+	ExplicitBounds []float64 `json:"explicitBounds,omitempty"`
+}
+
+type otlpMetric struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Unit        string         `json:"unit"`
+	Sum         *otlpSum       `json:"sum,omitempty"`
+	Histogram   *otlpHistogram `json:"histogram,omitempty"`
+}
+
+// otlpDelta is AGGREGATION_TEMPORALITY_DELTA: each invocation reports its own measurements.
+const otlpDelta = 1
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpHistogram struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+}
+
+func otlpMetrics(cli *CLI, start, end time.Time, timings Timings, rows int64, queryErr error) interface{} {
+	status := "ok"
+	if queryErr != nil {
+		status = "error"
+	}
+	attrs := func(extra ...string) []otlpAttribute {
+		kvs := append([]string{"db", cli.DB, "server.url", cli.URL, "status", status}, extra...)
+		var a []otlpAttribute
+		for i := 0; i < len(kvs); i += 2 {
+			a = append(a, otlpAttribute{Key: kvs[i], Value: otlpValue{StringValue: kvs[i+1]}})
+		}
+		return a
+	}
+	point := func(a []otlpAttribute) otlpDataPoint {
+		return otlpDataPoint{
+			Attributes:        a,
+			StartTimeUnixNano: strconv.FormatInt(start.UnixNano(), 10),
+			TimeUnixNano:      strconv.FormatInt(end.UnixNano(), 10),
+		}
+	}
+
+	queries := point(attrs())
+	queries.AsInt = "1"
+	metrics := []otlpMetric{{
+		Name: "flightclub.queries", Description: "Queries run", Unit: "{query}",
+		Sum: &otlpSum{DataPoints: []otlpDataPoint{queries}, AggregationTemporality: otlpDelta, IsMonotonic: true},
+	}}
+	// the timings of failed queries aren't known
+	if queryErr == nil {
+		received := point(attrs())
+		received.AsInt = strconv.FormatInt(rows, 10)
+		metrics = append(metrics, otlpMetric{
+			Name: "flightclub.query.rows", Description: "Rows received", Unit: "{row}",
+			Sum: &otlpSum{DataPoints: []otlpDataPoint{received}, AggregationTemporality: otlpDelta, IsMonotonic: true},
+		})
+
+		var durations []otlpDataPoint
+		for _, phase := range []struct {
+			name string
+			d    time.Duration
+		}{
+			{"warmup", timings.Warmup}, {"execute", timings.Execute}, {"doget", timings.DoGet}, {"total", timings.Total()},
+		} {
+			p := point(attrs("phase", phase.name))
+			seconds := phase.d.Seconds()
+			p.Count, p.Sum, p.ExplicitBounds = "1", &seconds, otlpDurationBounds
+			p.BucketCounts = make([]string, len(otlpDurationBounds)+1)
+			for i := range p.BucketCounts {
+				p.BucketCounts[i] = "0"
+			}
+			bucket := len(otlpDurationBounds)
+			for i, bound := range otlpDurationBounds {
+				if seconds <= bound {
+					bucket = i
+					break
+				}
+			}
+			p.BucketCounts[bucket] = "1"
+			durations = append(durations, p)
+		}
+		metrics = append(metrics, otlpMetric{
+			Name: "flightclub.query.duration", Description: "Duration of each phase of the queries", Unit: "s",
+			Histogram: &otlpHistogram{DataPoints: durations, AggregationTemporality: otlpDelta},
+		})
+	}
+
+	service := os.Getenv("OTEL_SERVICE_NAME")
+	resource := parseOTELList(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if service != "" || resource["service.name"] == "" {
+		if service == "" {
+			service = "flightclub"
+		}
+		resource["service.name"] = service
+	}
+	var resourceAttrs []otlpAttribute
+	for k, v := range resource {
+		resourceAttrs = append(resourceAttrs, otlpAttribute{Key: k, Value: otlpValue{StringValue: v}})
+	}
+	sort.Slice(resourceAttrs, func(i, j int) bool { return resourceAttrs[i].Key < resourceAttrs[j].Key })
+
+	type scope struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{"attributes": resourceAttrs},
+			"scopeMetrics": []interface{}{map[string]interface{}{
+				"scope":   scope{Name: "flightclub", Version: getVersion()},
+				"metrics": metrics,
+			}},
+		}},
+	}
+}
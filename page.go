@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"golang.org/x/term"
+)
+
+// pagedQuery wraps query so that it returns only the given (zero based) page of rows.
+func pagedQuery(query string, size, page int) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	// on lines of its own, so that a trailing -- comment doesn't swallow the closing parenthesis
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) AS flightclub_page LIMIT %d OFFSET %d", query, size, page*size)
+}
+
+// printPages prints the query results a page at a time. When stdin is a terminal,
+// it prompts for navigating to the next or previous page.
+func printPages(ctx context.Context, w io.Writer, c *flightsql.Client, query string, opts printOptions, size, page int) (Timings, error) {
+	var timings Timings
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	input := bufio.NewReader(os.Stdin)
+	for {
+		rows, t, err := printQuery(ctx, w, c, pagedQuery(query, size, page), opts)
+		if err != nil {
+			return timings, err
+		}
+		timings.Add(t)
+
+		last := rows < int64(size)
+		if !interactive {
+			return timings, nil
+		}
+
+		fmt.Fprintf(os.Stderr, "-- page %d", page+1)
+		if last {
+			fmt.Fprint(os.Stderr, " (last)")
+		}
+		fmt.Fprint(os.Stderr, ": [n]ext, [p]rev, [q]uit? ")
+		line, err := input.ReadString('\n')
+		if err != nil {
+			return timings, nil
+		}
+		switch strings.TrimSpace(line) {
+		case "n", "":
+			if !last {
+				page++
+			}
+		case "p":
+			if page > 0 {
+				page--
+			}
+		case "q":
+			return timings, nil
+		}
+	}
+}
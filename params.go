@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// queryParam is a query parameter given as name=value.
+type queryParam struct {
+	Name  string
+	Value string
+}
+
+func (p *queryParam) UnmarshalText(text []byte) error {
+	name, value, ok := strings.Cut(string(text), "=")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid parameter %q, expecting name=value", text)
+	}
+	*p = queryParam{Name: name, Value: value}
+	return nil
+}
+
+// paramsRecord builds the record binding the parameters of a prepared statement, with one row per
+// element of rows. Values are converted to the types of the statement parameter schema, if the server
+// provides one, otherwise the type is inferred from the values of the first row.
+func paramsRecord(names []string, rows [][]string, schema *arrow.Schema) (arrow.Record, error) {
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		if schema != nil && i < schema.NumFields() {
+			fields[i] = schema.Field(i)
+			fields[i].Nullable = true
+		} else {
+			var first string
+			if len(rows) > 0 {
+				first = rows[0][i]
+			}
+			fields[i] = arrow.Field{Name: name, Type: inferParamType(first), Nullable: true}
+		}
+	}
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer b.Release()
+	for r, row := range rows {
+		if len(row) != len(names) {
+			return nil, fmt.Errorf("row %d: expecting %d parameters, got %d", r+1, len(names), len(row))
+		}
+		for i, value := range row {
+			if err := b.Field(i).AppendValueFromString(value); err != nil {
+				return nil, fmt.Errorf("parameter %q: cannot convert %q to %s: %w", names[i], value, fields[i].Type, err)
+			}
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+// inferParamType returns int64 or float64 for numeric values and string otherwise.
+func inferParamType(value string) arrow.DataType {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return arrow.PrimitiveTypes.Int64
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return arrow.PrimitiveTypes.Float64
+	}
+	return arrow.BinaryTypes.String
+}
+
+// printPrepared runs query as a prepared statement with the given parameters bound, and prints its results.
+func printPrepared(ctx context.Context, w io.Writer, c *flightsql.Client, query string, params []queryParam, opts printOptions) (int64, Timings, error) {
+	beforeExecute := time.Now()
+	stmt, err := c.Prepare(ctx, query)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	defer stmt.Close(ctx)
+
+	names := make([]string, len(params))
+	values := make([]string, len(params))
+	for i, p := range params {
+		names[i], values[i] = p.Name, p.Value
+	}
+	binding, err := paramsRecord(names, [][]string{values}, stmt.ParameterSchema())
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	defer binding.Release()
+	stmt.SetParameters(binding)
+
+	info, err := stmt.Execute(ctx)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	executeDuration := time.Since(beforeExecute)
+
+	replan := func(ctx context.Context) (*flight.FlightInfo, error) { return stmt.Execute(ctx) }
+	rows, timings, err := printInfo(ctx, w, c, info, replan, opts)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	return rows, timings.Add(Timings{Execute: executeDuration}), nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// paramsField describes a column of a --params-file, as listed in --params-schema files. It's a schemaField
+// (the format of --save-schema) with optional null markers and a time layout for timestamps and dates:
+//
+//	[{"name": "time", "type": "timestamp[us, tz=UTC]", "nullable": true, "format": "02/01/2006 15:04", "null": ["", "N/A"]}, ...]
+type paramsField struct {
+	schemaField
+	Null   []string `json:"null"`
+	Format string   `json:"format"`
+}
+
+// paramsSchema controls how the values of a --params-file are converted, instead of relying on
+// the parameter schema of the prepared statement or on inference from the first row.
+type paramsSchema struct {
+	fields map[string]paramsField
+	types  map[string]arrow.DataType
+}
+
+func loadParamsSchema(filename string) (*paramsSchema, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var fields []paramsField
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+	s := &paramsSchema{fields: map[string]paramsField{}, types: map[string]arrow.DataType{}}
+	for _, f := range fields {
+		dt, err := parseArrowType(f.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s: column %q: %w", filename, f.Name, err)
+		}
+		s.fields[f.Name], s.types[f.Name] = f, dt
+	}
+	return s, nil
+}
+
+// record converts rows of parameters named by names into a record.
+func (s *paramsSchema) record(names []string, rows [][]string) (arrow.Record, error) {
+	fields := make([]arrow.Field, len(names))
+	for i, name := range names {
+		f, ok := s.fields[name]
+		if !ok {
+			return nil, fmt.Errorf("parameter %q is missing from the params schema", name)
+		}
+		fields[i] = arrow.Field{Name: name, Type: s.types[name], Nullable: f.Nullable}
+	}
+
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer b.Release()
+	for r, row := range rows {
+		if len(row) != len(names) {
+			return nil, fmt.Errorf("row %d: expecting %d parameters, got %d", r+1, len(names), len(row))
+		}
+		for i, value := range row {
+			if err := s.fields[names[i]].append(b.Field(i), value); err != nil {
+				return nil, fmt.Errorf("row %d: parameter %q: %w", r+1, names[i], err)
+			}
+		}
+	}
+	return b.NewRecord(), nil
+}
+
+func (f paramsField) append(b array.Builder, value string) error {
+	for _, null := range f.Null {
+		if value == null {
+			if !f.Nullable {
+				return fmt.Errorf("null value %q in a non-nullable column", value)
+			}
+			b.AppendNull()
+			return nil
+		}
+	}
+	if f.Format != "" {
+		switch b := b.(type) {
+		case *array.TimestampBuilder:
+			t, err := time.Parse(f.Format, value)
+			if err != nil {
+				return err
+			}
+			ts, err := arrow.TimestampFromTime(t, b.Type().(*arrow.TimestampType).Unit)
+			if err != nil {
+				return err
+			}
+			b.Append(ts)
+			return nil
+		case *array.Date32Builder:
+			t, err := time.Parse(f.Format, value)
+			if err != nil {
+				return err
+			}
+			b.Append(arrow.Date32FromTime(t))
+			return nil
+		case *array.Date64Builder:
+			t, err := time.Parse(f.Format, value)
+			if err != nil {
+				return err
+			}
+			b.Append(arrow.Date64FromTime(t))
+			return nil
+		}
+	}
+	if err := b.AppendValueFromString(value); err != nil {
+		return fmt.Errorf("cannot convert %q to %s: %w", value, b.Type(), err)
+	}
+	return nil
+}
+
+var timestampTypeRE = regexp.MustCompile(`^timestamp\[(s|ms|us|ns)(?:, tz=(.*))?\]$`)
+
+// parseArrowType parses the name of an Arrow type, as printed by DataType.String.
+// Only the types that can be read from a single CSV value are supported.
+func parseArrowType(s string) (arrow.DataType, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "bool":
+		return arrow.FixedWidthTypes.Boolean, nil
+	case "int8":
+		return arrow.PrimitiveTypes.Int8, nil
+	case "int16":
+		return arrow.PrimitiveTypes.Int16, nil
+	case "int32":
+		return arrow.PrimitiveTypes.Int32, nil
+	case "int64":
+		return arrow.PrimitiveTypes.Int64, nil
+	case "uint8":
+		return arrow.PrimitiveTypes.Uint8, nil
+	case "uint16":
+		return arrow.PrimitiveTypes.Uint16, nil
+	case "uint32":
+		return arrow.PrimitiveTypes.Uint32, nil
+	case "uint64":
+		return arrow.PrimitiveTypes.Uint64, nil
+	case "float32":
+		return arrow.PrimitiveTypes.Float32, nil
+	case "float64":
+		return arrow.PrimitiveTypes.Float64, nil
+	case "utf8", "string":
+		return arrow.BinaryTypes.String, nil
+	case "large_utf8":
+		return arrow.BinaryTypes.LargeString, nil
+	case "binary":
+		return arrow.BinaryTypes.Binary, nil
+	case "date32":
+		return arrow.FixedWidthTypes.Date32, nil
+	case "date64":
+		return arrow.FixedWidthTypes.Date64, nil
+	}
+	if m := timestampTypeRE.FindStringSubmatch(s); m != nil {
+		units := map[string]arrow.TimeUnit{"s": arrow.Second, "ms": arrow.Millisecond, "us": arrow.Microsecond, "ns": arrow.Nanosecond}
+		return &arrow.TimestampType{Unit: units[m[1]], TimeZone: m[2]}, nil
+	}
+	return nil, fmt.Errorf("unsupported type %q", s)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// readParamsFile reads rows of parameters from a CSV file, whose header line names the parameters,
+// or from a JSON file (*.json) holding an array of objects, whose keys name the parameters.
+func readParamsFile(filename string) (names []string, rows [][]string, err error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".json") {
+		names, rows, err = parseParamsJSON(b)
+	} else {
+		names, rows, err = parseParamsCSV(b)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return names, rows, nil
+}
+
+func parseParamsCSV(b []byte) ([]string, [][]string, error) {
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("missing header line")
+	}
+	return records[0], records[1:], nil
+}
+
+func parseParamsJSON(b []byte) ([]string, [][]string, error) {
+	var objects []json.RawMessage
+	if err := json.Unmarshal(b, &objects); err != nil {
+		return nil, nil, err
+	}
+	var (
+		names []string
+		rows  [][]string
+	)
+	for i, o := range objects {
+		// decode token by token to preserve the order of the keys, which is the order of the parameters
+		dec := json.NewDecoder(bytes.NewReader(o))
+		dec.UseNumber()
+		if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+			return nil, nil, fmt.Errorf("row %d: expecting an object", i+1)
+		}
+		var keys, row []string
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			var v interface{}
+			if err := dec.Decode(&v); err != nil {
+				return nil, nil, err
+			}
+			var s string
+			switch v := v.(type) {
+			case string:
+				s = v
+			case json.Number:
+				s = v.String()
+			case bool:
+				s = strconv.FormatBool(v)
+			default:
+				return nil, nil, fmt.Errorf("row %d: unsupported value for %q: %v", i+1, key, v)
+			}
+			keys, row = append(keys, key.(string)), append(row, s)
+		}
+		if names == nil {
+			names = keys
+		} else if strings.Join(keys, ",") != strings.Join(names, ",") {
+			return nil, nil, fmt.Errorf("row %d: expecting parameters %v, got %v", i+1, names, keys)
+		}
+		rows = append(rows, row)
+	}
+	return names, rows, nil
+}
+
+// runParamsFile executes the prepared query with the rows of parameters read from filename.
+// If schemaFile is set, it tells how to convert the parameters (see paramsField).
+// The results are discarded after going through onRecord, if set.
+func runParamsFile(ctx context.Context, c *flightsql.Client, query, filename, schemaFile string, batch bool, onRecord func(arrow.Record) error) (Timings, error) {
+	names, rows, err := readParamsFile(filename)
+	if err != nil {
+		return Timings{}, err
+	}
+	var types *paramsSchema
+	if schemaFile != "" {
+		if types, err = loadParamsSchema(schemaFile); err != nil {
+			return Timings{}, err
+		}
+	}
+	if len(rows) == 0 {
+		return Timings{}, fmt.Errorf("%s: no rows of parameters", filename)
+	}
+	return runParamsRows(ctx, c, query, names, rows, types, batch, onRecord)
+}
+
+// runParamsRows executes the prepared query once for each row of parameters (or once with all the rows bound
+// as a single batch) discarding the results, and prints the status of each execution followed by timing statistics.
+func runParamsRows(ctx context.Context, c *flightsql.Client, query string, names []string, rows [][]string, types *paramsSchema, batch bool, onRecord func(arrow.Record) error) (Timings, error) {
+	stmt, err := c.Prepare(ctx, query)
+	if err != nil {
+		return Timings{}, err
+	}
+	defer stmt.Close(ctx)
+
+	batches := make([][][]string, 0, len(rows))
+	if batch {
+		batches = append(batches, rows)
+	} else {
+		for _, row := range rows {
+			batches = append(batches, [][]string{row})
+		}
+	}
+
+	var (
+		total   Timings
+		samples benchSamples
+		failed  int
+	)
+	table := newStatsTable("Row", "Parameters", "Rows", "Execute", "DoGet", "Status")
+	for i, b := range batches {
+		label, params := strconv.Itoa(i+1), strings.Join(b[0], ", ")
+		if batch {
+			label, params = fmt.Sprintf("1-%d", len(rows)), fmt.Sprintf("%d rows", len(rows))
+		}
+
+		n, t, err := runPreparedRows(ctx, c, stmt, names, b, types, onRecord)
+		status := "ok"
+		if err != nil {
+			status = err.Error()
+			failed++
+		} else {
+			total.Add(t)
+			samples.add(n, t)
+		}
+		table.Append([]string{label, params, strconv.FormatInt(n, 10), t.Execute.String(), t.DoGet.String(), status})
+	}
+	table.Render()
+
+	if len(samples.Total) > 0 {
+		fmt.Println()
+		printBench(samples)
+	}
+	if failed > 0 {
+		return total, fmt.Errorf("%d of %d executions failed", failed, len(batches))
+	}
+	return total, nil
+}
+
+// runPreparedRows binds rows to stmt, executes it and fetches the results, passing them to onRecord if set,
+// and returning the number of rows received.
+// The parameters are converted according to types if set, otherwise to the parameter schema of stmt.
+func runPreparedRows(ctx context.Context, c *flightsql.Client, stmt *flightsql.PreparedStatement, names []string, rows [][]string, types *paramsSchema, onRecord func(arrow.Record) error) (int64, Timings, error) {
+	var (
+		binding arrow.Record
+		err     error
+	)
+	if types != nil {
+		binding, err = types.record(names, rows)
+	} else {
+		binding, err = paramsRecord(names, rows, stmt.ParameterSchema())
+	}
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	defer binding.Release()
+	stmt.SetParameters(binding)
+
+	beforeExecute := time.Now()
+	info, err := stmt.Execute(ctx)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	executeDuration := time.Since(beforeExecute)
+
+	var n int64
+	replan := func(ctx context.Context) (*flight.FlightInfo, error) { return stmt.Execute(ctx) }
+	timings, err := fetchRecords(ctx, c, info, replan, func(record arrow.Record) error {
+		n += record.NumRows()
+		if onRecord != nil {
+			return onRecord(record)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	return n, timings.Add(Timings{Execute: executeDuration}), nil
+}
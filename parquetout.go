@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/parquet"
+	"github.com/apache/arrow/go/v15/parquet/compress"
+	"github.com/apache/arrow/go/v15/parquet/pqarrow"
+)
+
+// parquetWriter writes the records unchanged to a Parquet file, preserving the original schema.
+type parquetWriter struct {
+	w  io.Writer
+	fw *pqarrow.FileWriter
+	// schema is the one announced by the server, for writing empty results
+	schema *arrow.Schema
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{w: w}
+}
+
+func (p *parquetWriter) announceSchema(schema *arrow.Schema) {
+	p.schema = schema
+}
+
+func (p *parquetWriter) WriteRecord(record arrow.Record) error {
+	if p.fw == nil {
+		if err := p.open(record.Schema()); err != nil {
+			return err
+		}
+	}
+	return p.fw.WriteBuffered(record)
+}
+
+func (p *parquetWriter) open(schema *arrow.Schema) error {
+	props := parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy))
+	// hide Close from the file writer, which would otherwise close our output
+	fw, err := pqarrow.NewFileWriter(schema, struct{ io.Writer }{p.w}, props, pqarrow.NewArrowWriterProperties(pqarrow.WithStoreSchema()))
+	if err != nil {
+		return err
+	}
+	p.fw = fw
+	return nil
+}
+
+// Close completes the file. Without any records, it's written with the announced schema, or no columns
+// if the server didn't tell, so that it's still a valid Parquet file.
+func (p *parquetWriter) Close() error {
+	if p.fw == nil {
+		schema := p.schema
+		if schema == nil {
+			schema = arrow.NewSchema(nil, nil)
+		}
+		if err := p.open(schema); err != nil {
+			return err
+		}
+	}
+	return p.fw.Close()
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// pivotMaxRows is the number of rows past which --pivot gives up: each row becomes a column.
+const pivotMaxRows = 100
+
+// pivotWriter swaps the rows and columns of the results, which are buffered, before writing them with out:
+// each column becomes a row, holding the column name followed by its (rendered) value in each row.
+type pivotWriter struct {
+	out  resultWriter
+	opts printOptions
+
+	header []string
+	rows   [][]string
+}
+
+func newPivotWriter(out resultWriter, opts printOptions) *pivotWriter {
+	return &pivotWriter{out: out, opts: opts}
+}
+
+func (p *pivotWriter) WriteRecord(record arrow.Record) error {
+	if p.header == nil {
+		p.header = getHeader(record)
+	}
+	if len(p.rows)+int(record.NumRows()) > pivotMaxRows {
+		return fmt.Errorf("--pivot is meant for small results, but there are more than %d rows", pivotMaxRows)
+	}
+	rows, err := p.opts.renderRecord(record)
+	if err != nil {
+		return err
+	}
+	p.rows = append(p.rows, rows...)
+	return nil
+}
+
+func (p *pivotWriter) Close() error {
+	if p.opts.StableOutput {
+		if err := sortRows(p.rows, p.header, p.opts.SortKey); err != nil {
+			return err
+		}
+	}
+
+	fields := []arrow.Field{{Name: "column", Type: arrow.BinaryTypes.String}}
+	for r := range p.rows {
+		fields = append(fields, arrow.Field{Name: strconv.Itoa(r + 1), Type: arrow.BinaryTypes.String})
+	}
+	b := array.NewRecordBuilder(memory.DefaultAllocator, arrow.NewSchema(fields, nil))
+	defer b.Release()
+	for c, name := range p.header {
+		b.Field(0).(*array.StringBuilder).Append(name)
+		for r, row := range p.rows {
+			b.Field(r + 1).(*array.StringBuilder).Append(row[c])
+		}
+	}
+	record := b.NewRecord()
+	defer record.Release()
+
+	if err := p.out.WriteRecord(record); err != nil {
+		return err
+	}
+	return p.out.Close()
+}
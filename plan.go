@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// planNode is an operator of a query plan, as printed by EXPLAIN.
+type planNode struct {
+	Label  string
+	Parent int // index of the parent node, -1 for the root
+}
+
+// explainedPlan is one of the plans (e.g. logical_plan, physical_plan) returned by EXPLAIN.
+type explainedPlan struct {
+	Type  string
+	Nodes []planNode
+}
+
+// printPlan runs an EXPLAIN query and writes its plans as a Graphviz (dot) or Mermaid diagram.
+func printPlan(ctx context.Context, w io.Writer, c *flightsql.Client, query string, format string) (int64, Timings, error) {
+	beforeExecute := time.Now()
+	info, err := c.Execute(ctx, query)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	executeDuration := time.Since(beforeExecute)
+
+	var (
+		plans []explainedPlan
+		rows  int64
+	)
+	timings, err := fetchRecords(ctx, c, info, executor(c, query), func(record arrow.Record) error {
+		header := getHeader(record)
+		typeCol, planCol := columnIndex(header, "plan_type"), columnIndex(header, "plan")
+		if planCol < 0 {
+			return fmt.Errorf("not an EXPLAIN result: no plan column")
+		}
+		var o renderOptions
+		for r := 0; r < int(record.NumRows()); r++ {
+			rows++
+			planType := "plan"
+			if typeCol >= 0 {
+				s, err := o.renderText(record.Column(typeCol), r)
+				if err != nil {
+					return err
+				}
+				planType = s
+			}
+			text, err := o.renderText(record.Column(planCol), r)
+			if err != nil {
+				return err
+			}
+			plans = append(plans, parsePlan(planType, text))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, Timings{}, err
+	}
+
+	switch format {
+	case "dot":
+		writeDot(w, plans)
+	case "mermaid":
+		writeMermaid(w, plans)
+	default:
+		return 0, Timings{}, fmt.Errorf("unsupported plan format %q", format)
+	}
+
+	return rows, timings.Add(Timings{Execute: executeDuration}), nil
+}
+
+// parsePlan parses an indented plan tree, where each level of indentation denotes a child operator.
+func parsePlan(planType, text string) explainedPlan {
+	plan := explainedPlan{Type: planType}
+
+	// stack of (indentation, node index) of the current path from the root
+	type frame struct{ indent, node int }
+	var stack []frame
+	for _, line := range strings.Split(text, "\n") {
+		label := strings.TrimSpace(line)
+		if label == "" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := -1
+		if len(stack) > 0 {
+			parent = stack[len(stack)-1].node
+		}
+		plan.Nodes = append(plan.Nodes, planNode{Label: label, Parent: parent})
+		stack = append(stack, frame{indent, len(plan.Nodes) - 1})
+	}
+	return plan
+}
+
+func writeDot(w io.Writer, plans []explainedPlan) {
+	fmt.Fprintln(w, "digraph plan {")
+	fmt.Fprintln(w, `  node [shape=box, fontname="monospace"];`)
+	for p, plan := range plans {
+		fmt.Fprintf(w, "  subgraph cluster_%d {\n    label=%q;\n", p, plan.Type)
+		for n, node := range plan.Nodes {
+			fmt.Fprintf(w, "    n%d_%d [label=%q];\n", p, n, node.Label)
+		}
+		fmt.Fprintln(w, "  }")
+		for n, node := range plan.Nodes {
+			if node.Parent >= 0 {
+				fmt.Fprintf(w, "  n%d_%d -> n%d_%d;\n", p, node.Parent, p, n)
+			}
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+func writeMermaid(w io.Writer, plans []explainedPlan) {
+	fmt.Fprintln(w, "flowchart TD")
+	for p, plan := range plans {
+		fmt.Fprintf(w, "  subgraph p%d [%q]\n", p, plan.Type)
+		for n, node := range plan.Nodes {
+			fmt.Fprintf(w, "    n%d_%d[\"%s\"]\n", p, n, strings.ReplaceAll(node.Label, `"`, "#quot;"))
+		}
+		fmt.Fprintln(w, "  end")
+		for n, node := range plan.Nodes {
+			if node.Parent >= 0 {
+				fmt.Fprintf(w, "  n%d_%d --> n%d_%d\n", p, node.Parent, p, n)
+			}
+		}
+	}
+}
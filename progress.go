@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// progressEvent is a machine-readable progress event, written as a JSON line by --progress-json.
+type progressEvent struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"` // connected, executed, endpoint_started, endpoint_finished or done
+	Endpoint *int      `json:"endpoint,omitempty"`
+	// Endpoints is the number of endpoints of the query, for executed events.
+	Endpoints int    `json:"endpoints,omitempty"`
+	Rows      int64  `json:"rows,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// progressWriter emits progress events to a file descriptor, so that wrappers can show progress bars.
+// A nil progressWriter discards the events.
+type progressWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newProgressWriter(fd int) *progressWriter {
+	return &progressWriter{enc: json.NewEncoder(os.NewFile(uintptr(fd), "progress"))}
+}
+
+func (p *progressWriter) emit(e progressEvent) {
+	if p == nil {
+		return
+	}
+	e.Time = time.Now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enc.Encode(e)
+}
+
+func (p *progressWriter) endpoint(event string, i int, rows int64) {
+	p.emit(progressEvent{Event: event, Endpoint: &i, Rows: rows})
+}
+
+func (p *progressWriter) done(err error) {
+	e := progressEvent{Event: "done"}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	p.emit(e)
+}
+
+type progressKey struct{}
+
+func withProgress(ctx context.Context, p *progressWriter) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// progressFrom returns the progress writer of ctx, or nil if progress events are disabled.
+func progressFrom(ctx context.Context) *progressWriter {
+	p, _ := ctx.Value(progressKey{}).(*progressWriter)
+	return p
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// projectedQuery wraps query so that it returns only the given columns.
+func projectedQuery(query string, columns []string) string {
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = `"` + strings.ReplaceAll(c, `"`, `""`) + `"`
+	}
+	// on lines of its own, so that a trailing -- comment doesn't swallow the closing parenthesis
+	return fmt.Sprintf("SELECT %s FROM (\n%s\n) AS flightclub_project", strings.Join(quoted, ", "), query)
+}
+
+// printProjected prints only the given columns of the query results.
+// The projection is pushed to the server by rewriting the query; if the server rejects the rewritten query,
+// when executing it or fetching it before any rows arrive, the full results are fetched and the other columns
+// are dropped client-side.
+func printProjected(ctx context.Context, w io.Writer, c *flightsql.Client, query string, columns []string, opts printOptions) (int64, Timings, error) {
+	projected := projectedQuery(query, columns)
+	beforeExecute := time.Now()
+	info, err := c.Execute(ctx, projected)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Projection: client-side (server rejected the rewritten query: %v)\n", err)
+		opts.Project = columns
+		return printQuery(ctx, w, c, query, opts)
+	}
+	executeDuration := time.Since(beforeExecute)
+
+	// the output is held until the first record, to be discarded if fetching fails before
+	out := &heldWriter{w: w}
+	release := func(arrow.Record) error { return out.release() }
+	fetchOpts := opts
+	fetchOpts.OnRecord = release
+	if opts.OnRecord != nil {
+		fetchOpts.OnRecord = chainOnRecord(release, opts.OnRecord)
+	}
+	rows, timings, err := printInfo(ctx, out, c, info, executor(c, projected), fetchOpts)
+	if err != nil && !out.released {
+		fmt.Fprintf(os.Stderr, "Projection: client-side (server failed the rewritten query: %v)\n", err)
+		opts.Project = columns
+		return printQuery(ctx, w, c, query, opts)
+	}
+	if rerr := out.release(); err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	return rows, timings.Add(Timings{Execute: executeDuration}), nil
+}
+
+// heldWriter holds what's written to it until released.
+type heldWriter struct {
+	w        io.Writer
+	held     bytes.Buffer
+	released bool
+}
+
+func (h *heldWriter) Write(p []byte) (int, error) {
+	if h.released {
+		return h.w.Write(p)
+	}
+	return h.held.Write(p)
+}
+
+// release writes out what's held, and lets the further writes through.
+func (h *heldWriter) release() error {
+	if h.released {
+		return nil
+	}
+	h.released = true
+	fmt.Fprintln(os.Stderr, "Projection: server-side (query rewrite)")
+	_, err := h.w.Write(h.held.Bytes())
+	h.held.Reset()
+	return err
+}
+
+// projectRecord returns a record with only the given columns of record, in the given order.
+func projectRecord(record arrow.Record, columns []string) (arrow.Record, error) {
+	header := getHeader(record)
+	fields := make([]arrow.Field, len(columns))
+	cols := make([]arrow.Array, len(columns))
+	for i, name := range columns {
+		c := columnIndex(header, name)
+		if c < 0 {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		fields[i], cols[i] = record.Schema().Field(c), record.Column(c)
+	}
+	metadata := record.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &metadata), cols, record.NumRows()), nil
+}
@@ -2,8 +2,17 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/apache/arrow/go/v15/arrow"
@@ -12,6 +21,8 @@ import (
 	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
 	"github.com/olekukonko/tablewriter"
 	"golang.org/x/term"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Timings struct {
@@ -38,67 +49,581 @@ func (t *Timings) Total() time.Duration {
 	return t.Warmup + t.Execute + t.DoGet
 }
 
-func printQuery(ctx context.Context, w io.Writer, c *flightsql.Client, query string) (Timings, error) {
+// printOptions controls how query results are printed.
+type printOptions struct {
+	renderOptions
+
+	// Format is the output format: table (default), json, ndjson, csv, tsv or parquet.
+	Format string
+
+	// NoHeader omits the header line of the csv and tsv formats.
+	NoHeader bool
+
+	// CSVDelimiter and CSVQuote, if set, replace the field delimiter and quote character of the csv and tsv formats,
+	// and DecimalComma writes floats and decimals with a decimal comma, for European locales.
+	CSVDelimiter rune
+	CSVQuote     rune
+	DecimalComma bool
+
+	// StableOutput sorts the rows before printing, by SortKey columns or by all columns if empty.
+	StableOutput bool
+	SortKey      []string
+
+	// ShowNullCounts prints the number of NULL values of each column in the table footer.
+	ShowNullCounts bool
+
+	// ShowTypes prints the Arrow type of each column below its name in the table header.
+	ShowTypes bool
+
+	// TableBufferRows is the number of rows buffered to align the columns of the table format,
+	// past which the rows are streamed (0 means unlimited).
+	TableBufferRows int
+
+	// SubtotalBy, if set, is the column whose groups of rows are followed by subtotals in table output.
+	SubtotalBy string
+
+	// Pivot swaps rows and columns, for small results with many columns.
+	Pivot bool
+
+	// MaxRows stops fetching once this many rows have been received (0 means unlimited).
+	MaxRows int64
+
+	// MaxBytes aborts fetching once more data than this has been received (0 means unlimited).
+	MaxBytes byteSize
+
+	// Summary is where summaries such as per-statement timings are printed (stdout if nil).
+	Summary io.Writer
+
+	// Project, if set, keeps only these columns of the received records.
+	Project []string
+
+	// Transform, if set, replaces the received records before they are written.
+	Transform func(arrow.Record) (arrow.Record, error)
+
+	// Join, if set, extends the rows with the columns of a local lookup table.
+	Join *lookupTable
+
+	// Grep, if set, keeps only the rows matching it.
+	Grep *rowFilter
+
+	// Distinct drops the duplicate rows of each result, remembering the last DistinctMemory distinct rows
+	// if set (see deduplicator).
+	Distinct       bool
+	DistinctMemory int
+
+	// Redact maps the columns to redact to their redaction mode, "hash" or "mask".
+	Redact map[string]string
+
+	// ExpectSchema, if set, fails the query before fetching data when the result schema differs.
+	ExpectSchema []schemaField
+	// SaveSchema, if set, is the file where the result schema is saved, for use with ExpectSchema.
+	SaveSchema string
+
+	// OnRecord, if set, is called with every received record before it's written.
+	OnRecord func(arrow.Record) error
+
+	// OnStatementRecord, if set, is called with every received record of a multi-statement run,
+	// along with the (zero based) index of its statement, before OnRecord.
+	OnStatementRecord func(stmt int, record arrow.Record) error
+
+	// OnSchema, if set, is called with the schema announced by the server before fetching the records,
+	// unless they are reshaped (see reshapes), for the hooks that must act even if no records arrive.
+	OnSchema func(*arrow.Schema)
+}
+
+// renderOptions controls how values are rendered as text.
+type renderOptions struct {
+	// NormalizeFloats formats floats with fewer significant digits,
+	// hiding last-digit noise caused by nondeterministic aggregation order.
+	NormalizeFloats bool
+
+	// DurationFormat is how durations are rendered: human (default, e.g. 1m30s), seconds or nanos.
+	DurationFormat string
+
+	// BoolFormat is how booleans are rendered: t/f (default), true/false or 1/0.
+	BoolFormat string
+
+	// NullString, if set, is how NULLs are rendered, instead of the default of the output format.
+	NullString *string
+
+	// TimeZone, if set, is the zone timestamps are shown in, instead of the one of their type (or UTC).
+	TimeZone *time.Location
+	// ShowTZOffset appends the UTC offset to timestamps.
+	ShowTZOffset bool
+
+	// BinaryFormat is how binary values are rendered: hex (\x0102), base64, escape (like PostgreSQL's bytea
+	// escape format), or the Go notation of byte slices ([1 2]) by default.
+	BinaryFormat string
+}
+
+func printQuery(ctx context.Context, w io.Writer, c *flightsql.Client, query string, opts printOptions) (int64, Timings, error) {
+	phasesFrom(ctx).enter("execute")
 	beforeExecute := time.Now()
 	info, err := c.Execute(ctx, query)
 	if err != nil {
-		return Timings{}, err
+		return 0, Timings{}, err
 	}
 	executeDuration := time.Since(beforeExecute)
 
-	timings, err := printInfo(ctx, w, c, info)
+	rows, timings, err := printInfo(ctx, w, c, info, executor(c, query), opts)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+
+	return rows, timings.Add(Timings{Execute: executeDuration}), nil
+}
+
+// printInfo prints the data of info. If replan is not nil, it's used to obtain a new FlightInfo if tickets expire.
+func printInfo(ctx context.Context, w io.Writer, c *flightsql.Client, info *flight.FlightInfo, replan planner, opts printOptions) (int64, Timings, error) {
+	out, err := newResultWriter(w, opts)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+
+	var totalRows int64
+	budget := byteBudget{max: opts.MaxBytes}
+	schema := schemaCheck{expected: opts.ExpectSchema, save: opts.SaveSchema}
+	if len(opts.Project) == 0 {
+		// with a client-side projection, the announced schema still has all the columns
+		if err := schema.check(infoSchema(info)); err != nil {
+			return 0, Timings{}, err
+		}
+	}
+
+	if announced := infoSchema(info); announced != nil && !opts.reshapes() {
+		if a, ok := out.(schemaAnnouncer); ok {
+			a.announceSchema(announced)
+		}
+		if opts.OnSchema != nil {
+			opts.OnSchema(announced)
+		}
+	}
+
+	// canceled to stop fetching once --max-rows rows have been received
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	truncated := false
+	var dedup *deduplicator
+	if opts.Distinct {
+		dedup = newDeduplicator(opts.DistinctMemory)
+	}
+
+	timings, err := fetchRecords(ctx, c, info, replan, func(record arrow.Record) error {
+		if err := budget.add(record); err != nil {
+			return err
+		}
+		if len(opts.Project) > 0 {
+			projected, err := projectRecord(record, opts.Project)
+			if err != nil {
+				return err
+			}
+			defer projected.Release()
+			record = projected
+		}
+		if opts.Transform != nil {
+			transformed, err := opts.Transform(record)
+			if err != nil {
+				return err
+			}
+			defer transformed.Release()
+			record = transformed
+		}
+		if err := schema.check(record.Schema()); err != nil {
+			return err
+		}
+		if opts.Join != nil {
+			joined, err := opts.Join.join(record, opts.renderOptions)
+			if err != nil {
+				return err
+			}
+			defer joined.Release()
+			record = joined
+		}
+		if opts.Grep != nil {
+			filtered, err := opts.Grep.filter(record, opts.renderOptions)
+			if err != nil {
+				return err
+			}
+			defer filtered.Release()
+			record = filtered
+		}
+		if dedup != nil {
+			distinct, err := dedup.filter(record, opts.renderOptions)
+			if err != nil {
+				return err
+			}
+			defer distinct.Release()
+			record = distinct
+		}
+		if opts.MaxRows > 0 && totalRows+record.NumRows() > opts.MaxRows {
+			head := record.NewSlice(0, opts.MaxRows-totalRows)
+			defer head.Release()
+			record, truncated = head, true
+		}
+		totalRows += record.NumRows()
+		if len(opts.Redact) > 0 {
+			redacted, err := redactRecord(record, opts.Redact, opts.renderOptions)
+			if err != nil {
+				return err
+			}
+			defer redacted.Release()
+			record = redacted
+		}
+		if opts.OnRecord != nil {
+			if err := opts.OnRecord(record); err != nil {
+				return err
+			}
+		}
+
+		if err := out.WriteRecord(record); err != nil {
+			return err
+		}
+		if truncated {
+			cancel(errMaxRows)
+			return errMaxRows
+		}
+		return nil
+	})
+	if truncated && errors.Is(err, errMaxRows) {
+		err = nil
+	}
 	if err != nil {
-		return Timings{}, err
+		// still print what we got so far
+		out.Close()
+		return 0, Timings{}, err
+	}
+	phasesFrom(ctx).enter("render")
+	if err := out.Close(); err != nil {
+		return 0, Timings{}, err
+	}
+	if removed := dedup.removedRows(); removed > 0 {
+		fmt.Fprintf(os.Stderr, "Removed %d duplicate rows\n", removed)
+	}
+	if truncated {
+		if info.TotalRecords > 0 {
+			fmt.Fprintf(os.Stderr, "Results truncated to %d rows (of %d)\n", opts.MaxRows, info.TotalRecords)
+		} else {
+			fmt.Fprintf(os.Stderr, "Results truncated to %d rows\n", opts.MaxRows)
+		}
 	}
 
-	return timings.Add(Timings{Execute: executeDuration}), nil
+	return totalRows, timings, nil
 }
 
-func printInfo(ctx context.Context, w io.Writer, c *flightsql.Client, info *flight.FlightInfo) (Timings, error) {
+// reshapes tells whether the records written have a different schema from the ones received.
+func (o printOptions) reshapes() bool {
+	return len(o.Project) > 0 || o.Transform != nil || o.Join != nil || len(o.Redact) > 0
+}
+
+// errMaxRows stops fetching the results once opts.MaxRows rows have been received.
+var errMaxRows = errors.New("enough rows received")
+
+// resultWriter writes query results in some output format.
+type resultWriter interface {
+	WriteRecord(record arrow.Record) error
+	// Close writes any buffered output. The underlying writer is not closed.
+	Close() error
+}
+
+// schemaAnnouncer is implemented by the result writers needing the schema of the results even if no records
+// arrive, to write a valid file. They are given the schema announced by the server, if any.
+type schemaAnnouncer interface {
+	announceSchema(schema *arrow.Schema)
+}
+
+// binaryFormats are the output formats that can't be written to a terminal.
+var binaryFormats = map[string]bool{"parquet": true, "arrow": true, "xlsx": true}
+
+// formatForFile returns the output format matching the extension of filename, defaulting to table.
+func formatForFile(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return "csv"
+	case ".tsv":
+		return "tsv"
+	case ".json":
+		return "json"
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".parquet":
+		return "parquet"
+	case ".arrow":
+		return "arrow"
+	case ".xlsx":
+		return "xlsx"
+	default:
+		return "table"
+	}
+}
+
+func newResultWriter(w io.Writer, opts printOptions) (resultWriter, error) {
+	if opts.Pivot {
+		// the pivoted rows are sorted before being transposed
+		inner := opts
+		inner.Pivot, inner.StableOutput, inner.ShowTypes = false, false, false
+		out, err := newResultWriter(w, inner)
+		if err != nil {
+			return nil, err
+		}
+		return newPivotWriter(out, opts), nil
+	}
+	var out resultWriter
+	switch opts.Format {
+	case "", "table":
+		// sorts the rows itself
+		return newTableWriter(w, opts), nil
+	case "json":
+		out = newJSONWriter(w, opts, false)
+	case "ndjson":
+		out = newJSONWriter(w, opts, true)
+	case "csv":
+		out = newCSVWriter(w, opts, ',')
+	case "tsv":
+		out = newCSVWriter(w, opts, '\t')
+	case "parquet":
+		out = newParquetWriter(w)
+	case "arrow":
+		out = newArrowWriter(w)
+	case "xlsx":
+		out = newXLSXWriter(w, opts)
+	case "expanded":
+		out = newExpandedWriter(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported output format %q", opts.Format)
+	}
+	if opts.StableOutput {
+		return newSortedWriter(out, opts), nil
+	}
+	return out, nil
+}
+
+// tableWriter renders results as an aligned text table.
+// The rows are buffered to align the columns, up to opts.TableBufferRows: past that, the table is
+// printed and the following rows are streamed (see streamRows).
+type tableWriter struct {
+	w     io.Writer
+	table *tablewriter.Table
+	opts  printOptions
+
+	totalRows  int64
+	header     []string
+	types      []string
+	rows       [][]string
+	nullCounts []int
+
+	// widths are the widths of the columns so far, and streaming is set once the rows are streamed
+	widths    []int
+	streaming bool
+
+	subtotals *subtotals
+}
+
+func newTableWriter(w io.Writer, opts printOptions) *tableWriter {
+	t := &tableWriter{w: w, table: newResultTable(w), opts: opts}
+	if opts.SubtotalBy != "" {
+		t.subtotals = newSubtotals(opts.SubtotalBy)
+	}
+	return t
+}
+
+func newResultTable(w io.Writer) *tablewriter.Table {
 	table := tablewriter.NewWriter(w)
 	table.SetAutoFormatHeaders(false)
 	table.SetRowLine(false)
 	table.SetBorder(false)
 	table.SetAutoWrapText(true)
 	//	table.SetBorders(tablewriter.Border{Top: true})
+	return table
+}
 
-	defer table.Render()
+func (t *tableWriter) WriteRecord(record arrow.Record) error {
+	t.totalRows += record.NumRows()
+	t.header = getHeader(record)
+	if t.opts.ShowTypes {
+		t.types = getTypes(record)
+	}
 
-	var doGetDuration time.Duration
-	totalRows := 0
-	var header []string
+	if t.nullCounts == nil {
+		t.nullCounts = make([]int, record.NumCols())
+	}
+	for i, col := range record.Columns() {
+		t.nullCounts[i] += col.NullN()
+	}
 
-	for _, endpoint := range info.Endpoint {
-		beforeDoGet := time.Now()
-		reader, err := c.DoGet(ctx, endpoint.GetTicket())
+	rendered, err := t.opts.renderRecord(record)
+	if err != nil {
+		return err
+	}
+	if t.subtotals != nil {
+		if err := t.subtotals.resolve(record); err != nil {
+			return err
+		}
+	}
+	if t.opts.StableOutput {
+		t.rows = append(t.rows, rendered...)
+		return nil
+	}
+	if t.subtotals != nil {
+		rendered = t.subtotals.add(rendered)
+	}
+	if t.streaming {
+		t.streamRows(rendered)
+		return nil
+	}
+	t.table.AppendBulk(rendered)
+	if t.opts.TableBufferRows > 0 {
+		t.measure(rendered)
+		if t.totalRows > int64(t.opts.TableBufferRows) {
+			t.startStreaming()
+		}
+	}
+	return nil
+}
+
+func (t *tableWriter) Close() error {
+	if t.streaming {
+		if t.subtotals != nil {
+			t.streamRows(t.subtotals.flush())
+		}
+		t.closeStream()
+		return nil
+	}
+	defer t.table.Render()
+
+	if t.opts.StableOutput {
+		if err := sortRows(t.rows, t.header, t.opts.SortKey); err != nil {
+			return err
+		}
+		if t.subtotals != nil {
+			t.rows = t.subtotals.add(t.rows)
+		}
+		t.table.AppendBulk(t.rows)
+	}
+	if t.subtotals != nil {
+		t.table.AppendBulk(t.subtotals.flush())
+	}
+
+	t.setHeader()
+	t.setFooter(t.table)
+	return nil
+}
+
+func (t *tableWriter) setHeader() {
+	if t.opts.ShowTypes {
+		// a header cell with a newline is printed on two header rows, unless it's reflowed by auto wrapping
+		header := make([]string, len(t.header))
+		for i := range t.header {
+			header[i] = t.header[i] + "\n" + t.types[i]
+		}
+		t.table.SetAutoWrapText(false)
+		t.table.SetHeader(header)
+		t.table.SetAutoWrapText(true)
+	} else {
+		t.table.SetHeader(t.header)
+	}
+}
+
+// setFooter sets the footer of table: the NULL counts, if asked to, or the header again
+// if the table is taller than the terminal.
+func (t *tableWriter) setFooter(table *tablewriter.Table) {
+	_, height, _ := term.GetSize(0)
+	if t.opts.ShowNullCounts {
+		var footer []string
+		for _, n := range t.nullCounts {
+			footer = append(footer, fmt.Sprintf("NULLs: %d", n))
+		}
+		table.SetFooter(footer)
+	} else if (t.totalRows + 4) >= int64(height) {
+		table.SetFooter(t.header)
+	}
+}
+
+// planner obtains a FlightInfo, e.g. by executing a query.
+type planner func(context.Context) (*flight.FlightInfo, error)
+
+// executor returns a planner executing query.
+func executor(c *flightsql.Client, query string) planner {
+	return func(ctx context.Context) (*flight.FlightInfo, error) {
+		return c.Execute(ctx, query)
+	}
+}
+
+// forEachRecord fetches all the endpoints of info and calls fn for each record batch received.
+func forEachRecord(ctx context.Context, c *flightsql.Client, info *flight.FlightInfo, fn func(arrow.Record) error) (Timings, error) {
+	return fetchRecords(ctx, c, info, nil, fn)
+}
+
+// fetchRecords is like forEachRecord, but when fetching an endpoint fails because its ticket has expired
+// (typically after a long gap between Execute and DoGet), it obtains a fresh FlightInfo from replan
+// and resumes from the same endpoint. This happens at most once.
+// If fetching stops because ctx is done, the query is canceled on the server.
+func fetchRecords(ctx context.Context, c *flightsql.Client, info *flight.FlightInfo, replan planner, fn func(arrow.Record) error) (_ Timings, err error) {
+	defer func() {
 		if err != nil {
-			return Timings{}, fmt.Errorf("getting ticket failed: %w", err)
+			cancelOnServer(ctx, c, info)
 		}
-		doGetDuration += time.Since(beforeDoGet)
+	}()
+	var doGetDuration time.Duration
+	progress := progressFrom(ctx)
+	progress.emit(progressEvent{Event: "executed", Endpoints: len(info.Endpoint)})
+	phases := phasesFrom(ctx)
+	phases.enter("fetch")
+	batches := batchStatsFrom(ctx)
 
-		for reader.Next() {
-			record := reader.Record()
-			totalRows += int(record.NumRows())
-			header = getHeader(record)
+	replanned := false
+	for i := 0; i < len(info.Endpoint); i++ {
+		received := false
+		var (
+			fnErr error
+			rows  int64
+		)
+		progress.endpoint("endpoint_started", i, 0)
 
-			if err := printRecord(table, record); err != nil {
-				return Timings{}, err
+		beforeDoGet := time.Now()
+		reader, err := doGetEndpoint(ctx, c, info.Endpoint[i])
+		if err != nil {
+			err = fmt.Errorf("getting ticket failed: %w", err)
+		} else {
+			doGetDuration += time.Since(beforeDoGet)
+
+			for reader.Next() {
+				received = true
+				rows += reader.Record().NumRows()
+				phases.addRows(reader.Record().NumRows())
+				batches.add(reader.Record())
+				if fnErr = fn(reader.Record()); fnErr != nil {
+					break
+				}
+			}
+			reader.Release()
+			if fnErr != nil {
+				return Timings{DoGet: doGetDuration}, fnErr
 			}
-		}
-		reader.Release()
 
-		if err := reader.Err(); err != nil {
+			err = reader.Err()
+			if err == nil || err == io.EOF {
+				progress.endpoint("endpoint_finished", i, rows)
+			}
 			if err == io.EOF {
 				break
 			}
-			return Timings{}, err
 		}
-	}
 
-	table.SetHeader(header)
-	_, height, _ := term.GetSize(0)
-	if (totalRows + 4) >= height {
-		table.SetFooter(header)
+		if err != nil {
+			if replanned {
+				// the query is re-planned only once
+				return Timings{}, fmt.Errorf("after re-planning the query for an expired ticket: %w", err)
+			}
+			if replan == nil || received || !isExpiredTicket(err) {
+				return Timings{}, err
+			}
+			fmt.Fprintf(os.Stderr, "Ticket of endpoint %d expired (%v), re-planning the query\n", i, err)
+			if info, err = replan(ctx); err != nil {
+				return Timings{}, err
+			}
+			replanned = true
+			i--
+		}
 	}
 
 	timings := Timings{
@@ -107,6 +632,47 @@ func printInfo(ctx context.Context, w io.Writer, c *flightsql.Client, info *flig
 	return timings, nil
 }
 
+// isExpiredTicket reports whether err is the server rejecting a ticket it no longer knows about:
+// the error must be about the ticket, so that e.g. a missing table reported by DoGet isn't taken for one.
+func isExpiredTicket(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok {
+		return false
+	}
+	msg := strings.ToLower(st.Message())
+	switch st.Code() {
+	case codes.NotFound:
+		return strings.Contains(msg, "ticket") || strings.Contains(msg, "expired")
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.Internal:
+		return strings.Contains(msg, "ticket") &&
+			(strings.Contains(msg, "expired") || strings.Contains(msg, "unknown") || strings.Contains(msg, "not found"))
+	}
+	return false
+}
+
+// runQuery executes query and fetches all its results without rendering them.
+// It returns the number of rows received.
+func runQuery(ctx context.Context, c *flightsql.Client, query string) (int64, Timings, error) {
+	phasesFrom(ctx).enter("execute")
+	beforeExecute := time.Now()
+	info, err := c.Execute(ctx, query)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	executeDuration := time.Since(beforeExecute)
+
+	var rows int64
+	timings, err := fetchRecords(ctx, c, info, executor(c, query), func(record arrow.Record) error {
+		rows += record.NumRows()
+		return nil
+	})
+	if err != nil {
+		return 0, Timings{}, err
+	}
+
+	return rows, timings.Add(Timings{Execute: executeDuration}), nil
+}
+
 func getHeader(record arrow.Record) (header []string) {
 	for c := 0; c < int(record.NumCols()); c++ {
 		header = append(header, record.ColumnName(c))
@@ -114,31 +680,104 @@ func getHeader(record arrow.Record) (header []string) {
 	return header
 }
 
-func printRecord(table *tablewriter.Table, record arrow.Record) error {
+// getTypes returns the Arrow type of each column of record, or its extension type name.
+func getTypes(record arrow.Record) (types []string) {
+	for _, f := range record.Schema().Fields() {
+		if name := extensionName(f); name != "" {
+			types = append(types, "extension<"+name+">")
+		} else {
+			types = append(types, f.Type.String())
+		}
+	}
+	return types
+}
+
+func (o renderOptions) renderRecord(record arrow.Record) ([][]string, error) {
+	var rows [][]string
 	for r := 0; r < int(record.NumRows()); r++ {
 		var row []string
 		for c := 0; c < int(record.NumCols()); c++ {
-			s, err := renderText(record.Column(c), r)
+			s, err := o.renderColumn(record.Schema().Field(c), record.Column(c), r)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			row = append(row, s)
 		}
-		table.Append(row)
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func columnIndex(header []string, name string) int {
+	for i, h := range header {
+		if h == name {
+			return i
+		}
 	}
+	return -1
+}
 
+// sortRows sorts rendered rows by the given key columns, or by all columns if key is empty.
+func sortRows(rows [][]string, header []string, key []string) error {
+	cols, err := sortColumns(header, key)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return lessRow(rows[i], rows[j], cols) })
 	return nil
 }
 
-func renderText(column arrow.Array, row int) (string, error) {
+// sortColumns returns the indexes of the key columns, or of all the columns if key is empty.
+func sortColumns(header []string, key []string) ([]int, error) {
+	var cols []int
+	for _, k := range key {
+		i := columnIndex(header, k)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown sort key column %q", k)
+		}
+		cols = append(cols, i)
+	}
+	if len(cols) == 0 {
+		for i := range header {
+			cols = append(cols, i)
+		}
+	}
+	return cols, nil
+}
+
+// lessRow compares two rendered rows by the values of cols.
+func lessRow(a, b []string, cols []int) bool {
+	for _, c := range cols {
+		if a[c] != b[c] {
+			return a[c] < b[c]
+		}
+	}
+	return false
+}
+
+// renderColumn renders a value of a record column, taking into account extension types declared in the field.
+func (o renderOptions) renderColumn(field arrow.Field, column arrow.Array, row int) (string, error) {
+	if name := extensionName(field); name != "" {
+		if ext, ok := column.(array.ExtensionArray); ok {
+			column = ext.Storage()
+		}
+		return o.renderExtension(name, column, row)
+	}
+	return o.renderText(column, row)
+}
+
+func (o renderOptions) renderText(column arrow.Array, row int) (string, error) {
 	if column.IsNull(row) {
-		return "NULL", nil
+		return o.nullString("NULL"), nil
 	}
 	switch typedColumn := column.(type) {
+	case array.ExtensionArray:
+		return o.renderExtension(typedColumn.ExtensionType().ExtensionName(), typedColumn.Storage(), row)
 	case *array.Timestamp:
-		unit := typedColumn.DataType().(*arrow.TimestampType).Unit
-		return typedColumn.Value(row).ToTime(unit).Format(pgTimestampFormat), nil
+		tt := typedColumn.DataType().(*arrow.TimestampType)
+		return o.renderTimestamp(typedColumn.Value(row).ToTime(tt.Unit), tt.TimeZone), nil
 	case *array.Time32:
 		unit := typedColumn.DataType().(*arrow.Time32Type).Unit
 		return typedColumn.Value(row).ToTime(unit).Format(pgTimestampFormat), nil
@@ -150,13 +789,21 @@ func renderText(column arrow.Array, row int) (string, error) {
 	case *array.Date64:
 		return typedColumn.Value(row).ToTime().Format(pgTimestampFormat), nil
 	case *array.Duration:
-		m := typedColumn.DataType().(*arrow.DurationType).Unit.Multiplier()
-		return (time.Duration(typedColumn.Value(row)) * m).String(), nil
+		return o.renderDuration(typedColumn.Value(row), typedColumn.DataType().(*arrow.DurationType).Unit), nil
 	case *array.Float16:
+		if o.NormalizeFloats {
+			return strconv.FormatFloat(float64(typedColumn.Value(row).Float32()), 'g', 3, 32), nil
+		}
 		return fmt.Sprint(typedColumn.Value(row)), nil
 	case *array.Float32:
+		if o.NormalizeFloats {
+			return strconv.FormatFloat(float64(typedColumn.Value(row)), 'g', 6, 32), nil
+		}
 		return fmt.Sprint(typedColumn.Value(row)), nil
 	case *array.Float64:
+		if o.NormalizeFloats {
+			return strconv.FormatFloat(typedColumn.Value(row), 'g', 12, 64), nil
+		}
 		return fmt.Sprint(typedColumn.Value(row)), nil
 	case *array.Uint8:
 		return fmt.Sprint(typedColumn.Value(row)), nil
@@ -176,15 +823,108 @@ func renderText(column arrow.Array, row int) (string, error) {
 		return fmt.Sprint(typedColumn.Value(row)), nil
 	case *array.String:
 		return typedColumn.Value(row), nil
+	case *array.LargeString:
+		return typedColumn.Value(row), nil
 	case *array.Binary:
-		return fmt.Sprint(typedColumn.Value(row)), nil
-	case *array.Boolean:
-		if typedColumn.Value(row) {
-			return "t", nil
-		} else {
-			return "f", nil
+		return o.renderBinary(typedColumn.Value(row)), nil
+	case *array.LargeBinary:
+		return o.renderBinary(typedColumn.Value(row)), nil
+	case *array.FixedSizeBinary:
+		v := typedColumn.Value(row)
+		if len(v) == 16 {
+			// most likely a UUID
+			return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16]), nil
 		}
+		return o.renderBinary(v), nil
+	case *array.RunEndEncoded:
+		return o.renderText(typedColumn.Values(), typedColumn.GetPhysicalIndex(row))
+	case *array.Dictionary:
+		return o.renderText(typedColumn.Dictionary(), typedColumn.GetValueIndex(row))
+	case *array.Boolean:
+		return o.renderBool(typedColumn.Value(row)), nil
+	case *array.Decimal128:
+		scale := typedColumn.DataType().(*arrow.Decimal128Type).Scale
+		return formatDecimal(typedColumn.Value(row).BigInt(), scale), nil
+	case *array.Decimal256:
+		scale := typedColumn.DataType().(*arrow.Decimal256Type).Scale
+		return formatDecimal(typedColumn.Value(row).BigInt(), scale), nil
+	case *array.Map, array.ListLike, *array.Struct:
+		return o.renderNested(column, row)
 	default:
 		return "", fmt.Errorf("unsupported arrow type %q", column.DataType().Name())
 	}
 }
+
+func (o renderOptions) renderDuration(value arrow.Duration, unit arrow.TimeUnit) string {
+	d := time.Duration(value) * unit.Multiplier()
+	switch o.DurationFormat {
+	case "seconds":
+		return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	case "nanos":
+		return strconv.FormatInt(d.Nanoseconds(), 10)
+	default:
+		return d.String()
+	}
+}
+
+// formatDecimal formats the unscaled value of a decimal with exactly scale digits after the point,
+// e.g. 12345 with scale 2 as 123.45, and 5 with scale -2 as 500.
+func formatDecimal(unscaled *big.Int, scale int32) string {
+	digits := new(big.Int).Abs(unscaled).String()
+	if scale <= 0 {
+		digits += strings.Repeat("0", int(-scale))
+	} else {
+		if len(digits) <= int(scale) {
+			digits = strings.Repeat("0", int(scale)-len(digits)+1) + digits
+		}
+		digits = digits[:len(digits)-int(scale)] + "." + digits[len(digits)-int(scale):]
+	}
+	if unscaled.Sign() < 0 {
+		return "-" + digits
+	}
+	return digits
+}
+
+// nullString returns how NULLs are rendered: NullString if set, otherwise def.
+func (o renderOptions) nullString(def string) string {
+	if o.NullString != nil {
+		return *o.NullString
+	}
+	return def
+}
+
+func (o renderOptions) renderBinary(b []byte) string {
+	switch o.BinaryFormat {
+	case "hex":
+		return `\x` + hex.EncodeToString(b)
+	case "base64":
+		return base64.StdEncoding.EncodeToString(b)
+	case "escape":
+		var sb strings.Builder
+		for _, c := range b {
+			switch {
+			case c == '\\':
+				sb.WriteString(`\\`)
+			case c < 0x20 || c > 0x7e:
+				fmt.Fprintf(&sb, `\%03o`, c)
+			default:
+				sb.WriteByte(c)
+			}
+		}
+		return sb.String()
+	default:
+		return fmt.Sprint(b)
+	}
+}
+
+func (o renderOptions) renderBool(b bool) string {
+	format := o.BoolFormat
+	if format == "" {
+		format = "t/f"
+	}
+	t, f, _ := strings.Cut(format, "/")
+	if b {
+		return t
+	}
+	return f
+}
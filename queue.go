@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// QueueCmd runs the queries listed in a file with a pool of concurrent workers.
+type QueueCmd struct {
+	File                *os.File `arg:"" help:"File with one query per line (blank lines and -- comments are skipped, except -- flightclub: directives applying to the next query)"`
+	Workers             int      `short:"j" default:"4" help:"Number of concurrent workers"`
+	ConnectionPerWorker bool     `help:"Dial a separate connection for each worker instead of sharing one"`
+}
+
+// queueOutcome is the result of a single query of the queue.
+type queueOutcome struct {
+	Worker  int
+	Rows    int64
+	Timings Timings
+	Err     error
+}
+
+func (cmd *QueueCmd) Run(cli *Context) error {
+	if cmd.Workers < 1 {
+		return fmt.Errorf("--workers must be at least 1")
+	}
+	queries, err := readQueries(cmd.File)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	shared, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer shared.Close()
+
+	clients := make([]*flightsql.Client, cmd.Workers)
+	for w := range clients {
+		clients[w] = shared
+		if cmd.ConnectionPerWorker {
+			if clients[w], err = cli.dial(ctx); err != nil {
+				return err
+			}
+			defer clients[w].Close()
+		}
+	}
+
+	jobs := make(chan int)
+	outcomes := make([]queueOutcome, len(queries))
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for w, c := range clients {
+		wg.Add(1)
+		go func(worker int, c *flightsql.Client) {
+			defer wg.Done()
+			for i := range jobs {
+				rows, t, err := runQueued(ctx, c, queries[i])
+				outcomes[i] = queueOutcome{Worker: worker, Rows: rows, Timings: t, Err: err}
+			}
+		}(w, c)
+	}
+	for i := range queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	table := newStatsTable("#", "Worker", "Rows", "Execute", "DoGet", "Total", "Status")
+	failed := 0
+	for i, o := range outcomes {
+		status := "ok"
+		if o.Err != nil {
+			status = o.Err.Error()
+			failed++
+		}
+		table.Append([]string{
+			strconv.Itoa(i + 1), strconv.Itoa(o.Worker), strconv.FormatInt(o.Rows, 10),
+			o.Timings.Execute.String(), o.Timings.DoGet.String(), o.Timings.Total().String(), status,
+		})
+	}
+	table.Render()
+	fmt.Printf("\n%d queries, %d failed, %d workers, elapsed %s\n", len(queries), failed, cmd.Workers, elapsed)
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d queries failed", failed, len(queries))
+	}
+	return nil
+}
+
+// runQueued runs a query of the queue, honoring its directives.
+// Results are discarded unless an output directive is given.
+func runQueued(ctx context.Context, c *flightsql.Client, query string) (int64, Timings, error) {
+	d, err := parseDirectives(query)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	if d.Output == "" {
+		return runQuery(ctx, c, query)
+	}
+
+	f, err := os.Create(d.Output)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	defer f.Close()
+	format := d.Format
+	if format == "" {
+		format = formatForFile(d.Output)
+	}
+	rows, t, err := printQuery(ctx, f, c, query, printOptions{Format: format})
+	if err != nil {
+		return rows, t, err
+	}
+	return rows, t, f.Close()
+}
+
+// readQueries reads one query per line, skipping blank lines and SQL line comments.
+// Directive comments are kept and prepended to the query that follows them.
+func readQueries(f *os.File) ([]string, error) {
+	defer f.Close()
+
+	var (
+		queries []string
+		pending []string
+	)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, directivePrefix) {
+			pending = append(pending, line)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "--") {
+			continue
+		}
+		queries = append(queries, strings.Join(append(pending, line), "\n"))
+		pending = nil
+	}
+	return queries, scanner.Err()
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+const (
+	// redactMask replaces every non-NULL value of the masked columns.
+	redactMask = "****"
+	// redactHashLen is the number of hex digits kept from the SHA-256 of the hashed values.
+	redactHashLen = 16
+)
+
+// parseRedact parses --redact arguments of the form col1,col2[:hash|mask] into the redaction mode of each column.
+// Masking is the default.
+func parseRedact(args []string) (map[string]string, error) {
+	modes := map[string]string{}
+	for _, arg := range args {
+		columns, mode := arg, "mask"
+		if i := strings.LastIndex(arg, ":"); i >= 0 {
+			columns, mode = arg[:i], arg[i+1:]
+		}
+		if mode != "mask" && mode != "hash" {
+			return nil, fmt.Errorf("invalid --redact mode %q (expecting hash or mask)", mode)
+		}
+		for _, c := range strings.Split(columns, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				modes[c] = mode
+			}
+		}
+	}
+	return modes, nil
+}
+
+// redactRecord returns a record where the columns listed in modes are replaced by string columns holding
+// a mask, or a truncated SHA-256 of the rendered values (which preserves equality, e.g. for joins or grouping).
+// NULLs are left as they are.
+func redactRecord(record arrow.Record, modes map[string]string, opts renderOptions) (arrow.Record, error) {
+	header := getHeader(record)
+	for name := range modes {
+		if columnIndex(header, name) < 0 {
+			return nil, fmt.Errorf("unknown --redact column %q", name)
+		}
+	}
+
+	schema := record.Schema()
+	fields := make([]arrow.Field, len(header))
+	cols := make([]arrow.Array, len(header))
+	for c, name := range header {
+		field, col := schema.Field(c), record.Column(c)
+		mode, ok := modes[name]
+		if !ok {
+			fields[c], cols[c] = field, col
+			continue
+		}
+		b := array.NewStringBuilder(memory.DefaultAllocator)
+		for r := 0; r < col.Len(); r++ {
+			if col.IsNull(r) {
+				b.AppendNull()
+				continue
+			}
+			if mode == "mask" {
+				b.Append(redactMask)
+				continue
+			}
+			s, err := opts.renderColumn(field, col, r)
+			if err != nil {
+				b.Release()
+				return nil, err
+			}
+			h := sha256.Sum256([]byte(s))
+			b.Append(hex.EncodeToString(h[:])[:redactHashLen])
+		}
+		redacted := b.NewArray()
+		b.Release()
+		defer redacted.Release()
+		fields[c] = arrow.Field{Name: field.Name, Type: arrow.BinaryTypes.String, Nullable: field.Nullable, Metadata: field.Metadata}
+		cols[c] = redacted
+	}
+	metadata := schema.Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &metadata), cols, record.NumRows()), nil
+}
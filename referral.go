@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// reuseConnectionLocation is the location URI telling clients to fetch the endpoint from the server
+// that returned the FlightInfo.
+const reuseConnectionLocation = "arrow-flight-reuse-connection://?"
+
+// LocationCredentials are the credentials used to fetch data from an endpoint location, e.g.:
+//
+//	locations:
+//	  grpc+tls://eu.example.com:443:
+//	    token: secret
+//	    headers:
+//	      x-org-id: "42"
+type LocationCredentials struct {
+	Token   string            `yaml:"token"`
+	Headers map[string]string `yaml:"headers"`
+}
+
+type referralsKey struct{}
+
+// referrals follows FlightInfo endpoints that point to other servers, dialing each location once.
+type referrals struct {
+	home string // host:port of the server the client is connected to
+	cli  *CLI
+
+	mu      sync.Mutex
+	clients map[string]*flightsql.Client
+}
+
+// withReferrals returns a context that lets fetchRecords follow endpoints pointing to other servers.
+// The connections to other servers use the TLS settings of cli, and the credentials of the location from
+// the config file, if any, otherwise those of cli if the location uses TLS. Close them with closeReferrals.
+func withReferrals(ctx context.Context, home string, cli *CLI) context.Context {
+	return context.WithValue(ctx, referralsKey{}, &referrals{home: home, cli: cli, clients: map[string]*flightsql.Client{}})
+}
+
+// doGetEndpoint fetches the ticket of endpoint from the server it points to. An endpoint can list
+// several locations holding the same data: they are tried in turn until one of them answers.
+// Endpoints without locations (or pointing back to the same server) are fetched with c.
+func doGetEndpoint(ctx context.Context, c *flightsql.Client, endpoint *flight.FlightEndpoint) (*flight.Reader, error) {
+	locations := endpoint.GetLocation()
+	if len(locations) == 0 {
+		return doGet(ctx, c, endpoint.GetTicket())
+	}
+	var errs []error
+	for _, location := range locations {
+		client, epCtx, err := locationClient(ctx, c, location.GetUri())
+		if err == nil {
+			var reader *flight.Reader
+			if reader, err = doGet(epCtx, client, endpoint.GetTicket()); err == nil {
+				return reader, nil
+			}
+		}
+		if len(locations) > 1 {
+			fmt.Fprintf(os.Stderr, "Fetching endpoint from %s failed: %v\n", location.GetUri(), err)
+		}
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// locationClient returns the client and the context to use for fetching an endpoint at uri.
+func locationClient(ctx context.Context, c *flightsql.Client, uri string) (*flightsql.Client, context.Context, error) {
+	r, _ := ctx.Value(referralsKey{}).(*referrals)
+	if r == nil || uri == reuseConnectionLocation {
+		return c, ctx, nil
+	}
+	addr, cred, err := r.cli.parseLocation(uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	if addr == r.home {
+		return c, ctx, nil
+	}
+	creds, ok := r.cli.locations[uri]
+	if !ok {
+		creds, ok = r.cli.locations[addr]
+	}
+	// without credentials of its own, a location gets the ones of the main server, but only over TLS
+	forward := !ok && cred.Info().SecurityProtocol == "tls"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, found := r.clients[addr]
+	if !found {
+		fmt.Fprintf(os.Stderr, "Following endpoint referral to %s\n", uri)
+		var (
+			auth       flight.ClientAuthHandler
+			middleware []flight.ClientMiddleware
+		)
+		if forward {
+			auth, middleware = r.cli, []flight.ClientMiddleware{flight.CreateClientMiddleware(r.cli)}
+		} else if !ok {
+			fmt.Fprintf(os.Stderr, "Not sending credentials to %s in plaintext, set them in the locations of the config file if needed\n", uri)
+		}
+		opts := append(r.cli.dialOptions(), grpc.WithTransportCredentials(cred))
+		if client, err = flightsql.NewClientCtx(ctx, addr, auth, middleware, opts...); err != nil {
+			return nil, nil, fmt.Errorf("dialing endpoint location %s: %w", uri, err)
+		}
+		r.clients[addr] = client
+	}
+	if forward {
+		return client, ctx, nil
+	}
+	// none of the headers meant for the main server (tokens, helper and custom headers) leak to the location
+	md := metadata.Pairs("database", r.cli.DB)
+	if ok {
+		md.Set("authorization", "Token "+creds.Token)
+		for k, v := range creds.Headers {
+			md.Set(k, v)
+		}
+	}
+	return client, metadata.NewOutgoingContext(ctx, md), nil
+}
+
+// closeReferrals closes the connections to the endpoint locations followed with ctx.
+func closeReferrals(ctx context.Context) {
+	r, _ := ctx.Value(referralsKey{}).(*referrals)
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, client := range r.clients {
+		client.Close()
+		delete(r.clients, addr)
+	}
+}
+
+// parseLocation parses a Flight location URI (e.g. grpc+tls://host:port) into an address and transport credentials.
+// TLS locations trust the same CAs and present the same client certificate as the main server.
+func (cli *CLI) parseLocation(uri string) (string, credentials.TransportCredentials, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", nil, err
+	}
+	tlsConfig, err := cli.tlsConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	// --tls-server-name is about the main server
+	tlsConfig.ServerName = ""
+	switch u.Scheme {
+	case "grpc", "grpc+tcp":
+		return u.Host, insecure.NewCredentials(), nil
+	case "grpc+tls":
+		return u.Host, credentials.NewTLS(tlsConfig), nil
+	default:
+		return parseAddr(uri, tlsConfig)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+const histogramBuckets = 20
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>flightclub bench report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h2 { margin-top: 2em; }
+table { border-collapse: collapse; }
+td, th { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #f4f4f4; }
+td.text { text-align: left; font-family: monospace; }
+svg rect.bar { fill: #4a7fb5; }
+svg rect.bar:hover { fill: #f0a030; }
+svg text { font-size: 10px; fill: #555; }
+pre { background: #f4f4f4; padding: 1em; }
+</style>
+</head>
+<body>
+<h1>flightclub bench report</h1>
+<p>{{.Started.Format "2006-01-02 15:04:05 MST"}} &mdash; {{.URL}} / {{.DB}}</p>
+<pre>{{.Query}}</pre>
+
+<h2>Summary</h2>
+<table>
+<tr><th>Series</th><th>Requests</th><th>Errors</th><th>Req/s</th><th>Min</th><th>Mean</th><th>P50</th><th>P95</th><th>P99</th><th>Max</th></tr>
+{{range .Series}}<tr><td class="text">{{.Name}}</td><td>{{.Requests}}</td><td>{{.Errors}}</td><td>{{.Throughput}}</td>
+<td>{{.Stats.Min}}</td><td>{{.Stats.Mean}}</td><td>{{.Stats.P50}}</td><td>{{.Stats.P95}}</td><td>{{.Stats.P99}}</td><td>{{.Stats.Max}}</td></tr>
+{{end}}</table>
+
+<h2>Latency distribution</h2>
+{{range .Series}}<h3>{{.Name}}</h3>
+{{.Histogram}}
+{{end}}
+
+<h2>{{.TimelineTitle}}</h2>
+{{.Timeline}}
+
+{{if .Errors}}<h2>Errors</h2>
+<table>
+<tr><th>Count</th><th>Error</th></tr>
+{{range .Errors}}<tr><td>{{.Count}}</td><td class="text">{{.Message}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
+
+// reportSeries is a set of latencies measured under the same conditions (cold/warm runs, a ramp step).
+type reportSeries struct {
+	Name       string
+	Requests   int
+	Errors     int
+	Throughput string
+	Stats      durationStats
+	Histogram  template.HTML
+}
+
+type reportError struct {
+	Message string
+	Count   int
+}
+
+// writeHTMLReport renders the bench report as a self-contained HTML page with SVG charts.
+func (r *benchReport) writeHTMLReport(filename string) error {
+	data := struct {
+		*benchReport
+		Series        []reportSeries
+		TimelineTitle string
+		Timeline      template.HTML
+		Errors        []reportError
+	}{benchReport: r}
+
+	if len(r.Steps) > 0 {
+		errors := map[string]int{}
+		var labels []string
+		var throughputs []float64
+		for _, step := range r.Steps {
+			latencies := secondsToDurations(step.Latencies)
+			throughput := float64(len(latencies)) / step.Elapsed
+			data.Series = append(data.Series, reportSeries{
+				Name:       fmt.Sprintf("concurrency %d", step.Concurrency),
+				Requests:   len(latencies) + len(step.Errors),
+				Errors:     len(step.Errors),
+				Throughput: fmt.Sprintf("%.1f", throughput),
+				Stats:      newDurationStats(latencies),
+				Histogram:  histogramChart(latencies),
+			})
+			labels = append(labels, fmt.Sprintf("concurrency %d", step.Concurrency))
+			throughputs = append(throughputs, throughput)
+			for _, e := range step.Errors {
+				errors[e]++
+			}
+		}
+		data.TimelineTitle = "Throughput per step (req/s)"
+		data.Timeline = barChart(labels, throughputs, "%.1f req/s")
+
+		for msg, count := range errors {
+			data.Errors = append(data.Errors, reportError{Message: msg, Count: count})
+		}
+		sort.Slice(data.Errors, func(i, j int) bool { return data.Errors[i].Count > data.Errors[j].Count })
+	} else {
+		var labels []string
+		var totals []float64
+		for _, kind := range []string{"cold", "warm"} {
+			var latencies []time.Duration
+			var elapsed float64
+			for i, run := range r.Runs {
+				if run.Kind != kind {
+					continue
+				}
+				latencies = append(latencies, time.Duration(run.Total*float64(time.Second)))
+				elapsed += run.Total
+				labels = append(labels, fmt.Sprintf("%s run %d", kind, i))
+				totals = append(totals, run.Total)
+			}
+			if len(latencies) == 0 {
+				continue
+			}
+			data.Series = append(data.Series, reportSeries{
+				Name:       kind,
+				Requests:   len(latencies),
+				Throughput: fmt.Sprintf("%.1f", float64(len(latencies))/elapsed),
+				Stats:      newDurationStats(latencies),
+				Histogram:  histogramChart(latencies),
+			})
+		}
+		data.TimelineTitle = "Total latency per run (s)"
+		data.Timeline = barChart(labels, totals, "%.4fs")
+	}
+
+	var buf bytes.Buffer
+	if err := reportTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, buf.Bytes(), 0o644)
+}
+
+func secondsToDurations(seconds []float64) []time.Duration {
+	var ds []time.Duration
+	for _, s := range seconds {
+		ds = append(ds, time.Duration(s*float64(time.Second)))
+	}
+	return ds
+}
+
+// histogramChart renders the distribution of latencies as a bar chart with equal width buckets.
+func histogramChart(latencies []time.Duration) template.HTML {
+	if len(latencies) == 0 {
+		return ""
+	}
+	st := newDurationStats(latencies)
+	width := (st.Max - st.Min) / histogramBuckets
+	if width <= 0 {
+		width = 1
+	}
+
+	counts := make([]float64, histogramBuckets)
+	for _, l := range latencies {
+		b := int((l - st.Min) / width)
+		if b >= histogramBuckets {
+			b = histogramBuckets - 1
+		}
+		counts[b]++
+	}
+	labels := make([]string, histogramBuckets)
+	for i := range labels {
+		lo := st.Min + time.Duration(i)*width
+		labels[i] = fmt.Sprintf("%s - %s", lo, lo+width)
+	}
+	return barChart(labels, counts, "%.0f requests")
+}
+
+// barChart renders values as an SVG bar chart; hovering a bar shows its label and value.
+func barChart(labels []string, values []float64, valueFormat string) template.HTML {
+	const (
+		width  = 800.0
+		height = 200.0
+		margin = 20.0
+	)
+	if len(values) == 0 {
+		return ""
+	}
+
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	if max == 0 {
+		max = 1
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg width="%g" height="%g" xmlns="http://www.w3.org/2000/svg">`, width, height+margin)
+	barWidth := (width - margin) / float64(len(values))
+	for i, v := range values {
+		h := v / max * height
+		fmt.Fprintf(&buf, `<rect class="bar" x="%g" y="%g" width="%g" height="%g"><title>%s: %s</title></rect>`,
+			margin+float64(i)*barWidth, height-h, math.Max(barWidth-1, 1), h,
+			template.HTMLEscapeString(labels[i]), fmt.Sprintf(valueFormat, v))
+	}
+	fmt.Fprintf(&buf, `<text x="0" y="10">%s</text>`, template.HTMLEscapeString(fmt.Sprintf(valueFormat, max)))
+	fmt.Fprintf(&buf, `<text x="%g" y="%g">%s</text>`, margin, height+margin-4, template.HTMLEscapeString(labels[0]))
+	buf.WriteString(`</svg>`)
+
+	return template.HTML(buf.String())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// responseMetadata records the gRPC response headers and trailers of the GetFlightInfo (Execute)
+// and DoGet calls (--show-metadata), such as the query ID that correlates a query with the server logs.
+type responseMetadata struct {
+	mu    sync.Mutex
+	calls []*responseCall
+}
+
+type responseCall struct {
+	name     string
+	header   metadata.MD
+	trailer  metadata.MD
+	received bool
+}
+
+type responseCallKey struct{}
+
+// recordedCalls are the recorded gRPC methods.
+var recordedCalls = map[string]bool{
+	"GetFlightInfo": true,
+	"DoGet":         true,
+}
+
+func (m *responseMetadata) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	name := path.Base(info.FullMethodName)
+	if !recordedCalls[name] {
+		return ctx
+	}
+	call := &responseCall{name: name}
+	m.mu.Lock()
+	m.calls = append(m.calls, call)
+	m.mu.Unlock()
+	return context.WithValue(ctx, responseCallKey{}, call)
+}
+
+func (m *responseMetadata) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (m *responseMetadata) HandleConn(context.Context, stats.ConnStats) {}
+
+func (m *responseMetadata) HandleRPC(ctx context.Context, st stats.RPCStats) {
+	call, ok := ctx.Value(responseCallKey{}).(*responseCall)
+	if !ok {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch st := st.(type) {
+	case *stats.OutPayload:
+		// tell the Flight SQL command of GetFlightInfo, e.g. the statement from the warmup
+		if desc, ok := st.Payload.(*flight.FlightDescriptor); ok {
+			var cmd anypb.Any
+			if proto.Unmarshal(desc.Cmd, &cmd) == nil && cmd.TypeUrl != "" {
+				call.name += " " + cmd.TypeUrl[strings.LastIndex(cmd.TypeUrl, ".")+1:]
+			}
+		}
+	case *stats.InHeader:
+		call.header, call.received = st.Header, true
+	case *stats.InTrailer:
+		call.trailer, call.received = st.Trailer, true
+	}
+}
+
+// print writes the headers and trailers of each call, in the order the calls were made.
+func (m *responseMetadata) print(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, call := range m.calls {
+		if !call.received {
+			continue
+		}
+		fmt.Fprintf(w, "%s response headers:\n", call.name)
+		printMetadata(w, call.header)
+		fmt.Fprintf(w, "%s response trailers:\n", call.name)
+		printMetadata(w, call.trailer)
+	}
+}
+
+func printMetadata(w io.Writer, md metadata.MD) {
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, v := range md[k] {
+			fmt.Fprintf(w, "  %s: %s\n", k, v)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryQueryBackoff is the pause before the first retry of --retry-query, doubled at each further attempt.
+const retryQueryBackoff = time.Second
+
+// runRetrying runs the query, starting over up to --retry-query times if it fails: each attempt dials
+// a new connection, executes the query again and recreates the output file from scratch.
+// Only transient errors are retried (see retryable): interruptions, the --deadline and errors that would
+// happen again, like SQL errors, aren't.
+func (cmd *QueryCmd) runRetrying(cli *Context, art *artifacts) (Timings, error) {
+	backoff := retryQueryBackoff
+	for attempt := 1; ; attempt++ {
+		timings, err := cmd.run(cli, art)
+		if err == nil || attempt > cmd.RetryQuery || cli.base.Err() != nil || !retryable(err) {
+			return timings, err
+		}
+		fmt.Fprintf(os.Stderr, "Query failed: %v\nRetrying in %s (retry %d of %d)\n", err, backoff, attempt, cmd.RetryQuery)
+		if cmd.Output == "" {
+			fmt.Fprintln(os.Stderr, "The output of the failed attempt was already printed, the results start over")
+		}
+		select {
+		case <-time.After(backoff):
+		case <-cli.base.Done():
+			return timings, err
+		}
+		backoff *= 2
+	}
+}
+
+// retryable tells whether err is a transient failure worth starting over for: the server being unavailable,
+// overloaded or aborting the query, or the attempt timing out.
+func retryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
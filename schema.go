@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// schemaField describes a column of a result schema, as stored in --expect-schema files:
+//
+//	[{"name": "time", "type": "timestamp[ns, tz=UTC]", "nullable": false}, ...]
+type schemaField struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+}
+
+func describeSchema(schema *arrow.Schema) []schemaField {
+	fields := make([]schemaField, 0, schema.NumFields())
+	for _, f := range schema.Fields() {
+		fields = append(fields, schemaField{Name: f.Name, Type: f.Type.String(), Nullable: f.Nullable})
+	}
+	return fields
+}
+
+func loadSchema(filename string) ([]schemaField, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var fields []schemaField
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filename, err)
+	}
+	return fields, nil
+}
+
+func saveSchema(filename string, schema *arrow.Schema) error {
+	b, err := json.MarshalIndent(describeSchema(schema), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(b, '\n'), 0o644)
+}
+
+// infoSchema returns the schema of the results announced in info, or nil if the server didn't provide one.
+func infoSchema(info *flight.FlightInfo) *arrow.Schema {
+	if len(info.GetSchema()) == 0 {
+		return nil
+	}
+	schema, err := flight.DeserializeSchema(info.GetSchema(), memory.DefaultAllocator)
+	if err != nil {
+		return nil
+	}
+	return schema
+}
+
+// checkSchema returns an error listing the differences between the expected and the actual schema.
+func checkSchema(expected []schemaField, actual *arrow.Schema) error {
+	if diffs := diffSchema(expected, describeSchema(actual)); len(diffs) > 0 {
+		return fmt.Errorf("schema mismatch:\n  %s", strings.Join(diffs, "\n  "))
+	}
+	return nil
+}
+
+// diffSchema describes the columns of got that differ from expected.
+func diffSchema(expected, got []schemaField) []string {
+	var diffs []string
+	for i := 0; i < len(expected) || i < len(got); i++ {
+		switch {
+		case i >= len(got):
+			diffs = append(diffs, fmt.Sprintf("column %d: missing %s", i+1, expected[i]))
+		case i >= len(expected):
+			diffs = append(diffs, fmt.Sprintf("column %d: unexpected %s", i+1, got[i]))
+		case expected[i] != got[i]:
+			diffs = append(diffs, fmt.Sprintf("column %d: expected %s, got %s", i+1, expected[i], got[i]))
+		}
+	}
+	return diffs
+}
+
+func (f schemaField) String() string {
+	s := fmt.Sprintf("%s %s", f.Name, f.Type)
+	if !f.Nullable {
+		s += " not null"
+	}
+	return s
+}
+
+// schemaCheck checks the result schema against an expectation, and/or saves it, as soon as it's known:
+// from the FlightInfo if the server provides it, otherwise from the first record received.
+type schemaCheck struct {
+	expected []schemaField
+	save     string
+	done     bool
+}
+
+func (s *schemaCheck) check(schema *arrow.Schema) error {
+	if s.done || schema == nil || (s.expected == nil && s.save == "") {
+		return nil
+	}
+	s.done = true
+	if s.save != "" {
+		if err := saveSchema(s.save, schema); err != nil {
+			return err
+		}
+	}
+	if s.expected != nil {
+		return checkSchema(s.expected, schema)
+	}
+	return nil
+}
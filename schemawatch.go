@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/flight"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+)
+
+// SchemaWatchCmd periodically fetches the schemas of the selected tables and reports when they change.
+type SchemaWatchCmd struct {
+	Catalog       string        `help:"Only watch the tables of this catalog"`
+	SchemaPattern string        `help:"Only watch the tables of the schemas matching this pattern (% and _ wildcards)"`
+	TablePattern  string        `help:"Only watch the tables matching this pattern (% and _ wildcards)"`
+	Interval      time.Duration `default:"1m" help:"How often the schemas are fetched"`
+	OnChange      string        `help:"Shell command run on each change, with the table and the change in FLIGHT_CLUB_TABLE and FLIGHT_CLUB_CHANGE"`
+	ExitOnChange  bool          `help:"Exit with an error as soon as a change is detected"`
+}
+
+func (cmd *SchemaWatchCmd) Run(cli *Context) error {
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	opts := &flightsql.GetTablesOpts{
+		Catalog:                optionalString(cmd.Catalog),
+		DbSchemaFilterPattern:  optionalString(cmd.SchemaPattern),
+		TableNameFilterPattern: optionalString(cmd.TablePattern),
+		IncludeSchema:          true,
+	}
+
+	var last map[string][]schemaField
+	for {
+		current, err := tableSchemas(ctx, c, opts)
+		if err != nil {
+			return err
+		}
+		if last == nil {
+			fmt.Printf("%s watching %d tables\n", time.Now().Format(time.RFC3339), len(current))
+		} else if changes := schemaChanges(last, current); len(changes) > 0 {
+			for _, ch := range changes {
+				fmt.Printf("%s %s: %s\n", time.Now().Format(time.RFC3339), ch.table, ch.change)
+				if cmd.OnChange != "" {
+					if err := runChangeHook(cmd.OnChange, ch); err != nil {
+						fmt.Fprintf(os.Stderr, "on-change hook: %v\n", err)
+					}
+				}
+			}
+			if cmd.ExitOnChange {
+				return fmt.Errorf("%d schema changes detected", len(changes))
+			}
+		}
+		last = current
+		time.Sleep(cmd.Interval)
+	}
+}
+
+// tableSchemas returns the schemas of the tables listed by GetTables, keyed by their qualified name.
+func tableSchemas(ctx context.Context, c *flightsql.Client, opts *flightsql.GetTablesOpts) (map[string][]schemaField, error) {
+	info, err := c.GetTables(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	schemas := map[string][]schemaField{}
+	var o renderOptions
+	_, err = forEachRecord(ctx, c, info, func(record arrow.Record) error {
+		header := getHeader(record)
+		schemaCol := columnIndex(header, "table_schema")
+		if schemaCol < 0 {
+			return fmt.Errorf("server didn't return table schemas")
+		}
+		for r := 0; r < int(record.NumRows()); r++ {
+			var name []string
+			for _, col := range []string{"catalog_name", "db_schema_name", "table_name"} {
+				if i := columnIndex(header, col); i >= 0 && !record.Column(i).IsNull(r) {
+					s, err := o.renderText(record.Column(i), r)
+					if err != nil {
+						return err
+					}
+					name = append(name, s)
+				}
+			}
+			serialized, ok := record.Column(schemaCol).(*array.Binary)
+			if !ok || serialized.IsNull(r) {
+				continue
+			}
+			schema, err := flight.DeserializeSchema(serialized.Value(r), memory.DefaultAllocator)
+			if err != nil {
+				return err
+			}
+			schemas[strings.Join(name, ".")] = describeSchema(schema)
+		}
+		return nil
+	})
+	return schemas, err
+}
+
+type schemaChange struct {
+	table  string
+	change string
+}
+
+// schemaChanges lists the tables added, removed or whose schema changed, sorted by table name.
+func schemaChanges(before, after map[string][]schemaField) []schemaChange {
+	var changes []schemaChange
+	for table, fields := range after {
+		old, ok := before[table]
+		if !ok {
+			changes = append(changes, schemaChange{table, "table added"})
+			continue
+		}
+		for _, diff := range diffSchema(old, fields) {
+			changes = append(changes, schemaChange{table, diff})
+		}
+	}
+	for table := range before {
+		if _, ok := after[table]; !ok {
+			changes = append(changes, schemaChange{table, "table removed"})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].table < changes[j].table })
+	return changes
+}
+
+func runChangeHook(hook string, ch schemaChange) error {
+	cmd := exec.Command("/bin/sh", "-c", hook)
+	cmd.Env = append(os.Environ(), "FLIGHT_CLUB_TABLE="+ch.table, "FLIGHT_CLUB_CHANGE="+ch.change)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
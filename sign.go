@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// signedStatement is what the detached signature of an export vouches for: the exact bytes of the file,
+// and the canonical digest of the results it was written from, which doesn't depend on the output format.
+type signedStatement struct {
+	File         string    `json:"file"`
+	SHA256       string    `json:"sha256"`
+	Rows         int64     `json:"rows"`
+	ResultDigest string    `json:"result_digest"`
+	Query        string    `json:"query"`
+	URL          string    `json:"url"`
+	DB           string    `json:"db"`
+	SignedAt     time.Time `json:"signed_at"`
+}
+
+// signatureFile is the content of a detached signature (<export>.sig). The signature is over the compact
+// JSON encoding of Statement.
+type signatureFile struct {
+	Statement json.RawMessage `json:"statement"`
+	Algorithm string          `json:"algorithm"`
+	Signature string          `json:"signature"`
+}
+
+// resultDigest hashes the results in a canonical form, as the sum of the SHA-256 of each rendered row,
+// so that it neither depends on the order of the rows nor needs to hold them all.
+type resultDigest struct {
+	opts renderOptions
+	sum  big.Int
+	rows int64
+}
+
+func (d *resultDigest) add(record arrow.Record) error {
+	rows, err := d.opts.renderRecord(record)
+	if err != nil {
+		return err
+	}
+	var v big.Int
+	for _, row := range rows {
+		h := sha256.Sum256([]byte(strings.Join(row, "\x1f")))
+		d.sum.Add(&d.sum, v.SetBytes(h[:]))
+	}
+	d.rows += int64(len(rows))
+	return nil
+}
+
+func (d *resultDigest) String() string {
+	var mod big.Int
+	mod.Lsh(big.NewInt(1), 256)
+	return fmt.Sprintf("%064x", new(big.Int).Mod(&d.sum, &mod))
+}
+
+// chainOnRecord returns a printOptions.OnRecord callback calling first, then next (each if set).
+func chainOnRecord(first, next func(arrow.Record) error) func(arrow.Record) error {
+	if first == nil {
+		return next
+	}
+	if next == nil {
+		return first
+	}
+	return func(record arrow.Record) error {
+		if err := first(record); err != nil {
+			return err
+		}
+		return next(record)
+	}
+}
+
+// signExport writes the detached signature of the export filename to filename.sig.
+func signExport(filename string, key crypto.Signer, statement signedStatement) error {
+	sum, err := fileSHA256(filename)
+	if err != nil {
+		return err
+	}
+	statement.File, statement.SHA256, statement.SignedAt = filepath.Base(filename), sum, time.Now().UTC()
+	msg, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+
+	var (
+		alg string
+		sig []byte
+	)
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		alg = "ed25519"
+		sig, err = key.Sign(rand.Reader, msg, crypto.Hash(0))
+	case *ecdsa.PublicKey:
+		h := sha256.Sum256(msg)
+		alg = "ecdsa-sha256"
+		sig, err = key.Sign(rand.Reader, h[:], crypto.SHA256)
+	case *rsa.PublicKey:
+		h := sha256.Sum256(msg)
+		alg = "rsa-pkcs1v15-sha256"
+		sig, err = key.Sign(rand.Reader, h[:], crypto.SHA256)
+	default:
+		return fmt.Errorf("unsupported key type %T", key.Public())
+	}
+	if err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(signatureFile{Statement: msg, Algorithm: alg, Signature: base64.StdEncoding.EncodeToString(sig)}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename+".sig", append(b, '\n'), 0o644)
+}
+
+// verifySignature checks the detached signature sigFile of the export filename against the public key.
+func verifySignature(filename, sigFile string, pub crypto.PublicKey) (signedStatement, error) {
+	var statement signedStatement
+	b, err := os.ReadFile(sigFile)
+	if err != nil {
+		return statement, err
+	}
+	var sf signatureFile
+	if err := json.Unmarshal(b, &sf); err != nil {
+		return statement, fmt.Errorf("parsing %s: %w", sigFile, err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(sf.Signature)
+	if err != nil {
+		return statement, fmt.Errorf("parsing %s: %w", sigFile, err)
+	}
+
+	// the statement was signed as compact JSON, before being indented in the signature file
+	var msg bytes.Buffer
+	if err := json.Compact(&msg, sf.Statement); err != nil {
+		return statement, fmt.Errorf("parsing %s: %w", sigFile, err)
+	}
+	h := sha256.Sum256(msg.Bytes())
+	valid := false
+	switch pub := pub.(type) {
+	case ed25519.PublicKey:
+		valid = sf.Algorithm == "ed25519" && ed25519.Verify(pub, msg.Bytes(), sig)
+	case *ecdsa.PublicKey:
+		valid = sf.Algorithm == "ecdsa-sha256" && ecdsa.VerifyASN1(pub, h[:], sig)
+	case *rsa.PublicKey:
+		valid = sf.Algorithm == "rsa-pkcs1v15-sha256" && rsa.VerifyPKCS1v15(pub, crypto.SHA256, h[:], sig) == nil
+	default:
+		return statement, fmt.Errorf("unsupported key type %T", pub)
+	}
+	if !valid {
+		return statement, fmt.Errorf("invalid signature: %s wasn't signed by this key", sigFile)
+	}
+
+	if err := json.Unmarshal(sf.Statement, &statement); err != nil {
+		return statement, err
+	}
+	sum, err := fileSHA256(filename)
+	if err != nil {
+		return statement, err
+	}
+	if sum != statement.SHA256 {
+		return statement, fmt.Errorf("%s was modified after it was signed (sha256 %s, signed %s)", filename, sum, statement.SHA256)
+	}
+	return statement, nil
+}
+
+// loadSigner reads a PEM encoded private key (PKCS #8, SEC 1 or PKCS #1).
+func loadSigner(filename string) (crypto.Signer, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+	var key interface{}
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("%s: unsupported key type %T", filename, key)
+	}
+	return signer, nil
+}
+
+// loadPublicKey reads a PEM encoded public key, certificate or private key.
+func loadPublicKey(filename string) (crypto.PublicKey, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	signer, err := loadSigner(filename)
+	if err != nil {
+		return nil, err
+	}
+	return signer.Public(), nil
+}
+
+func readPEM(filename string) (*pem.Block, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", filename)
+	}
+	return block, nil
+}
+
+// VerifyCmd checks the detached signature of an export written with --sign.
+type VerifyCmd struct {
+	File      string `arg:"" type:"existingfile" help:"Signed export"`
+	PublicKey string `required:"" type:"existingfile" placeholder:"PEM" help:"Public key (or certificate) of the signer"`
+	Signature string `type:"existingfile" placeholder:"FILE" help:"Detached signature (default: the export file name with a .sig suffix)"`
+}
+
+func (cmd *VerifyCmd) Run(cli *Context) error {
+	pub, err := loadPublicKey(cmd.PublicKey)
+	if err != nil {
+		return err
+	}
+	sigFile := cmd.Signature
+	if sigFile == "" {
+		sigFile = cmd.File + ".sig"
+	}
+	statement, err := verifySignature(cmd.File, sigFile, pub)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: signature OK\n", cmd.File)
+	fmt.Printf("  signed at:     %s\n", statement.SignedAt.Format(time.RFC3339))
+	fmt.Printf("  query:         %s\n", abbreviate(statement.Query, 80))
+	fmt.Printf("  source:        %s (db %s)\n", statement.URL, statement.DB)
+	fmt.Printf("  rows:          %d\n", statement.Rows)
+	fmt.Printf("  result digest: %s\n", statement.ResultDigest)
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// sortedWriter buffers the records and writes their rows sorted to out when closed (--stable-output),
+// for the formats that write the rows as they come.
+type sortedWriter struct {
+	out     resultWriter
+	opts    printOptions
+	records []arrow.Record
+}
+
+func newSortedWriter(out resultWriter, opts printOptions) *sortedWriter {
+	return &sortedWriter{out: out, opts: opts}
+}
+
+func (s *sortedWriter) WriteRecord(record arrow.Record) error {
+	record.Retain()
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (s *sortedWriter) announceSchema(schema *arrow.Schema) {
+	if a, ok := s.out.(schemaAnnouncer); ok {
+		a.announceSchema(schema)
+	}
+}
+
+func (s *sortedWriter) Close() error {
+	defer func() {
+		for _, record := range s.records {
+			record.Release()
+		}
+	}()
+	if err := s.writeSorted(); err != nil {
+		s.out.Close()
+		return err
+	}
+	return s.out.Close()
+}
+
+// sortedRow is a row of a buffered record, with its rendered values.
+type sortedRow struct {
+	record arrow.Record
+	row    int
+	values []string
+}
+
+func (s *sortedWriter) writeSorted() error {
+	if len(s.records) == 0 {
+		return nil
+	}
+	cols, err := sortColumns(getHeader(s.records[0]), s.opts.SortKey)
+	if err != nil {
+		return err
+	}
+	var rows []sortedRow
+	for _, record := range s.records {
+		schema := record.Schema()
+		for r := 0; r < int(record.NumRows()); r++ {
+			values := make([]string, record.NumCols())
+			for c, col := range record.Columns() {
+				if values[c], err = s.opts.renderColumn(schema.Field(c), col, r); err != nil {
+					return err
+				}
+			}
+			rows = append(rows, sortedRow{record: record, row: r, values: values})
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return lessRow(rows[i].values, rows[j].values, cols) })
+
+	// consecutive rows of the same record are written as a single slice
+	for i := 0; i < len(rows); {
+		j := i + 1
+		for j < len(rows) && rows[j].record == rows[i].record && rows[j].row == rows[j-1].row+1 {
+			j++
+		}
+		slice := rows[i].record.NewSlice(int64(rows[i].row), int64(rows[j-1].row+1))
+		err := s.out.WriteRecord(slice)
+		slice.Release()
+		if err != nil {
+			return err
+		}
+		i = j
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"golang.org/x/term"
+)
+
+// sparkTicks are the bars of a sparkline, from the lowest value to the highest.
+var sparkTicks = []rune("▁▂▃▄▅▆▇█")
+
+// sparklines collects the values of numeric columns, printed as unicode sparklines after the results.
+type sparklines struct {
+	columns []string
+	// values of each column, in row order; NULLs are NaNs
+	values [][]float64
+}
+
+func newSparklines(columns []string) *sparklines {
+	return &sparklines{columns: columns, values: make([][]float64, len(columns))}
+}
+
+func (s *sparklines) add(record arrow.Record) error {
+	header := getHeader(record)
+	for i, name := range s.columns {
+		c := columnIndex(header, name)
+		if c < 0 {
+			return fmt.Errorf("unknown --sparkline column %q", name)
+		}
+		id := record.Schema().Field(c).Type.ID()
+		if !arrow.IsInteger(id) && !arrow.IsFloating(id) && !arrow.IsDecimal(id) {
+			return fmt.Errorf("--sparkline column %q isn't numeric", name)
+		}
+		col := record.Column(c)
+		for r := 0; r < col.Len(); r++ {
+			v := math.NaN()
+			if !col.IsNull(r) {
+				s, err := renderOptions{}.renderText(col, r)
+				if err != nil {
+					return err
+				}
+				v, _ = strconv.ParseFloat(s, 64)
+			}
+			s.values[i] = append(s.values[i], v)
+		}
+	}
+	return nil
+}
+
+// print writes a line per column with its sparkline, fitting the terminal width, and the range of its values.
+func (s *sparklines) print(w io.Writer) {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	label := 0
+	for _, name := range s.columns {
+		if len(name) > label {
+			label = len(name)
+		}
+	}
+	for i, name := range s.columns {
+		lo, hi := valueRange(s.values[i])
+		summary := fmt.Sprintf(" min %s, max %s, %d rows", formatFloat(lo), formatFloat(hi), len(s.values[i]))
+		fmt.Fprintf(w, "%-*s %s%s\n", label, name, sparkline(s.values[i], width-label-1-len(summary)), summary)
+	}
+}
+
+// sparkline renders values with at most width bars, each averaging a run of consecutive values
+// if there are more values than bars. Runs of NULLs are blank.
+func sparkline(values []float64, width int) string {
+	if width < 10 {
+		width = 10
+	}
+	if len(values) > width {
+		buckets := make([]float64, width)
+		for b := range buckets {
+			sum, n := 0.0, 0
+			for _, v := range values[b*len(values)/width : (b+1)*len(values)/width] {
+				if !math.IsNaN(v) {
+					sum, n = sum+v, n+1
+				}
+			}
+			buckets[b] = math.NaN()
+			if n > 0 {
+				buckets[b] = sum / float64(n)
+			}
+		}
+		values = buckets
+	}
+
+	lo, hi := valueRange(values)
+	var sb strings.Builder
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v) || math.IsInf(v, 0):
+			sb.WriteRune(' ')
+		case hi == lo:
+			sb.WriteRune(sparkTicks[len(sparkTicks)/2])
+		default:
+			sb.WriteRune(sparkTicks[int((v-lo)/(hi-lo)*float64(len(sparkTicks)-1)+0.5)])
+		}
+	}
+	return sb.String()
+}
+
+// valueRange returns the minimum and maximum finite values, or NaNs if there are none.
+func valueRange(values []float64) (lo, hi float64) {
+	lo, hi = math.NaN(), math.NaN()
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			continue
+		}
+		if math.IsNaN(lo) || v < lo {
+			lo = v
+		}
+		if math.IsNaN(hi) || v > hi {
+			hi = v
+		}
+	}
+	return lo, hi
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', 6, 64)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+)
+
+// splitStatements splits SQL text into statements at semicolons, ignoring semicolons that appear
+// inside quoted strings and identifiers, dollar-quoted strings and comments.
+//
+// Comments are kept with the statement that follows them. Statements containing nothing but
+// whitespace and comments are dropped.
+func splitStatements(sql string) []string {
+	var (
+		stmts   []string
+		start   int
+		hasCode bool
+	)
+	flush := func(end int) {
+		if hasCode {
+			stmts = append(stmts, strings.TrimSpace(sql[start:end]))
+		}
+		start, hasCode = end+1, false
+	}
+
+	for i := 0; i < len(sql); i++ {
+		switch ch := sql[i]; {
+		case ch == ';':
+			flush(i)
+
+		case ch == '\'' || ch == '"':
+			// a doubled quote is an escaped quote, which this loop handles as two adjacent strings
+			i = skipUntil(sql, i+1, string(ch))
+			hasCode = true
+
+		case ch == '-' && strings.HasPrefix(sql[i:], "--"):
+			i = skipUntil(sql, i+2, "\n")
+
+		case ch == '/' && strings.HasPrefix(sql[i:], "/*"):
+			i = skipBlockComment(sql, i)
+
+		case ch == '$':
+			if tag, ok := dollarTag(sql[i:]); ok {
+				i = skipUntil(sql, i+len(tag), tag)
+			}
+			hasCode = true
+
+		case ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r':
+			hasCode = true
+		}
+	}
+	flush(len(sql))
+
+	return stmts
+}
+
+// skipUntil returns the index of the last byte of the first occurrence of terminator in s at or after from,
+// or the index of the last byte of s if there is none.
+func skipUntil(s string, from int, terminator string) int {
+	if from > len(s) {
+		return len(s) - 1
+	}
+	if j := strings.Index(s[from:], terminator); j >= 0 {
+		return from + j + len(terminator) - 1
+	}
+	return len(s) - 1
+}
+
+// skipBlockComment returns the index of the last byte of the (possibly nested) block comment starting at i.
+func skipBlockComment(s string, i int) int {
+	depth := 0
+	for ; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "/*"):
+			depth++
+			i++
+		case strings.HasPrefix(s[i:], "*/"):
+			depth--
+			i++
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s) - 1
+}
+
+// dollarTag returns the dollar quote opening tag (e.g. "$$" or "$body$") at the start of s.
+// Positional parameters like $1 are not dollar quotes.
+func dollarTag(s string) (string, bool) {
+	for j := 1; j < len(s); j++ {
+		ch := s[j]
+		switch {
+		case ch == '$':
+			return s[:j+1], true
+		case ch == '_' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z':
+		case ch >= '0' && ch <= '9' && j > 1:
+		default:
+			return "", false
+		}
+	}
+	return "", false
+}
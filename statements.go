@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/olekukonko/tablewriter"
+)
+
+// runStatements executes the statements one after the other over the same client, printing the results
+// of each, followed by a table of per-statement timings. Unless continueOnError is set, it stops at the first failure.
+//
+// With jobs > 1, up to jobs statements run concurrently, in the order given by statementDeps, each buffering
+// its output, which is printed in the order of the statements. A statement depending on a failed one is skipped,
+// whereas run one after the other with continueOnError, every statement runs.
+func runStatements(ctx context.Context, w io.Writer, c *flightsql.Client, statements []string, opts printOptions, continueOnError bool, jobs int) (Timings, error) {
+	var (
+		total     Timings
+		totalRows int64
+		failed    int
+	)
+	deps, err := statementDeps(statements)
+	if err != nil {
+		return total, err
+	}
+	start := time.Now()
+	results := make([]*statementResult, len(statements))
+	for i := range results {
+		results[i] = &statementResult{done: make(chan struct{})}
+	}
+	if jobs > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer func() {
+			// the statements still running after a failure are abandoned
+			cancel()
+			wg.Wait()
+		}()
+		runConcurrently(ctx, &wg, c, statements, deps, results, serializeOnRecord(opts), jobs)
+	}
+
+	summary := opts.Summary
+	if summary == nil {
+		summary = os.Stdout
+	}
+	table := newStatsTableTo(summary, "#", "Statement", "Rows", "Execute", "DoGet", "Total", "Status")
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT,
+		tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+	for i, stmt := range statements {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		r := results[i]
+		if jobs > 1 {
+			<-r.done
+			if _, err := w.Write(r.out.Bytes()); err != nil {
+				return total, err
+			}
+		} else {
+			r.rows, r.timings, r.err = runStatement(ctx, w, c, i, stmt, opts)
+		}
+		status := "ok"
+		if r.err != nil {
+			status = r.err.Error()
+			failed++
+		} else {
+			total.Add(r.timings)
+			totalRows += r.rows
+		}
+		table.Append([]string{strconv.Itoa(i + 1), abbreviate(stmt, 50), strconv.FormatInt(r.rows, 10),
+			r.timings.Execute.String(), r.timings.DoGet.String(), r.timings.Total().String(), status})
+		if r.err != nil && !continueOnError {
+			break
+		}
+	}
+	table.Append([]string{"", "total", strconv.FormatInt(totalRows, 10),
+		total.Execute.String(), total.DoGet.String(), total.Total().String(), fmt.Sprintf("%d failed", failed)})
+	fmt.Fprintln(summary)
+	table.Render()
+	if jobs > 1 {
+		fmt.Fprintf(summary, "Wall time with %d jobs: %s\n", jobs, time.Since(start))
+	}
+
+	if failed > 0 {
+		return total, fmt.Errorf("%d of %d statements failed", failed, len(statements))
+	}
+	return total, nil
+}
+
+// runStatement prints the results of a statement, honoring its directives (see parseDirectives):
+// timeout limits how long it runs, and output sends its results to their own file, in the given format
+// or the one inferred from the file name. i is the index of the statement, passed to OnStatementRecord.
+func runStatement(ctx context.Context, w io.Writer, c *flightsql.Client, i int, stmt string, opts printOptions) (int64, Timings, error) {
+	d, err := parseDirectives(stmt)
+	if err != nil {
+		return 0, Timings{}, err
+	}
+	if hook := opts.OnStatementRecord; hook != nil {
+		opts.OnRecord = chainOnRecord(func(record arrow.Record) error { return hook(i, record) }, opts.OnRecord)
+	}
+	if d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout)
+		defer cancel()
+	}
+	if d.Format != "" {
+		opts.Format = d.Format
+	}
+	var f *os.File
+	if d.Output != "" {
+		if d.Format == "" {
+			opts.Format = formatForFile(d.Output)
+		}
+		if f, err = os.Create(d.Output); err != nil {
+			return 0, Timings{}, err
+		}
+		defer f.Close()
+		w = f
+	}
+	rows, timings, err := printQuery(ctx, w, c, stmt, opts)
+	if err != nil {
+		if d.Timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+			err = fmt.Errorf("timed out after %s: %w", d.Timeout, err)
+		}
+		return rows, timings, err
+	}
+	if f != nil {
+		return rows, timings, f.Close()
+	}
+	return rows, timings, nil
+}
+
+// statementResult is the outcome of a statement run concurrently, with its buffered output.
+type statementResult struct {
+	rows    int64
+	timings Timings
+	err     error
+	out     bytes.Buffer
+	done    chan struct{}
+}
+
+// runConcurrently starts the statements, each running once the statements it depends on are done
+// and at most jobs run at a time. Once ctx is canceled, the statements not started yet fail with its error.
+func runConcurrently(ctx context.Context, wg *sync.WaitGroup, c *flightsql.Client, statements []string, deps [][]int, results []*statementResult, opts printOptions, jobs int) {
+	slots := make(chan struct{}, jobs)
+	for i, stmt := range statements {
+		wg.Add(1)
+		go func(i int, r *statementResult, stmt string, deps []int) {
+			defer wg.Done()
+			defer close(r.done)
+			for _, dep := range deps {
+				select {
+				case <-results[dep].done:
+				case <-ctx.Done():
+					r.err = ctx.Err()
+					return
+				}
+			}
+			if r.err = failedDep(deps, results); r.err != nil {
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				r.err = ctx.Err()
+				return
+			}
+			defer func() { <-slots }()
+			r.rows, r.timings, r.err = runStatement(ctx, &r.out, c, i, stmt, opts)
+		}(i, results[i], stmt, deps[i])
+	}
+}
+
+// failedDep returns an error if one of the statements deps, which are done, failed.
+func failedDep(deps []int, results []*statementResult) error {
+	for _, dep := range deps {
+		if results[dep].err != nil {
+			return fmt.Errorf("skipped, statement %d failed", dep+1)
+		}
+	}
+	return nil
+}
+
+// serializeOnRecord returns opts with OnRecord and OnStatementRecord hooks safe to call from concurrent statements,
+// since the hooks (sparklines, histograms, counters, artifacts...) aren't.
+func serializeOnRecord(opts printOptions) printOptions {
+	var mu sync.Mutex
+	if next := opts.OnStatementRecord; next != nil {
+		opts.OnStatementRecord = func(stmt int, record arrow.Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return next(stmt, record)
+		}
+	}
+	if next := opts.OnRecord; next != nil {
+		opts.OnRecord = func(record arrow.Record) error {
+			mu.Lock()
+			defer mu.Unlock()
+			return next(record)
+		}
+	}
+	return opts
+}
+
+// abbreviate returns s on a single line, truncated to n characters.
+func abbreviate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
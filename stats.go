@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// durationStats summarizes a distribution of durations.
+type durationStats struct {
+	N      int
+	Min    time.Duration
+	Max    time.Duration
+	Mean   time.Duration
+	Stddev time.Duration
+	P50    time.Duration
+	P95    time.Duration
+	P99    time.Duration
+}
+
+func newDurationStats(samples []time.Duration) durationStats {
+	if len(samples) == 0 {
+		return durationStats{}
+	}
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	mean := sum / time.Duration(len(sorted))
+
+	var variance float64
+	for _, d := range sorted {
+		delta := float64(d - mean)
+		variance += delta * delta
+	}
+	variance /= float64(len(sorted))
+
+	return durationStats{
+		N:      len(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		Stddev: time.Duration(math.Sqrt(variance)),
+		P50:    percentile(sorted, 50),
+		P95:    percentile(sorted, 95),
+		P99:    percentile(sorted, 99),
+	}
+}
+
+// percentile returns the p-th percentile of sorted using the nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// stickyHeaders holds the routing tokens captured with --sticky-header: the first value of each
+// header (or trailer) returned by the server, sent back on every following call of the session,
+// so that servers routing them to the node that planned the query keep doing so.
+type stickyHeaders struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+// newStickyHeaders returns the sticky headers of a session, or nil without --sticky-header.
+func (cli *CLI) newStickyHeaders() *stickyHeaders {
+	if len(cli.StickyHeader) == 0 {
+		return nil
+	}
+	return &stickyHeaders{values: map[string]string{}}
+}
+
+// HeadersReceived captures the sticky headers of a response, if not captured yet.
+func (cli *CLI) HeadersReceived(ctx context.Context, md metadata.MD) {
+	s := cli.sticky
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range cli.StickyHeader {
+		key = strings.ToLower(key)
+		if _, ok := s.values[key]; ok {
+			continue
+		}
+		if v := md.Get(key); len(v) > 0 {
+			s.values[key] = v[0]
+			if cli.Verbose {
+				fmt.Fprintf(os.Stderr, "Sticky routing with %s: %s\n", key, v[0])
+			}
+		}
+	}
+}
+
+// withStickyHeaders adds the captured sticky headers to the outgoing metadata of ctx.
+func (cli *CLI) withStickyHeaders(ctx context.Context) context.Context {
+	s := cli.sticky
+	if s == nil {
+		return ctx
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.values) == 0 {
+		return ctx
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	for k, v := range s.values {
+		md.Set(k, v)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// subtotals inserts, between the rows of a table, a subtotal row after each group of consecutive rows
+// with the same value of a column, summing the numeric columns, and a grand total row at the end.
+// The results should be ordered by the group column.
+type subtotals struct {
+	column string
+
+	// key is the index of the group column, and kinds tells how each column is summed (if at all)
+	key   int
+	kinds []sumKind
+
+	group     string
+	groupRows int64
+	groupSums []*big.Rat
+	totalRows int64
+	totalSums []*big.Rat
+}
+
+// sumKind is how the sums of a column are computed and rendered.
+type sumKind struct {
+	numeric bool
+	float   bool
+	// scale is the number of digits after the point of decimals
+	scale int32
+}
+
+func newSubtotals(column string) *subtotals {
+	return &subtotals{column: column, key: -1}
+}
+
+// resolve finds the group column and the numeric columns in the schema of the results.
+func (s *subtotals) resolve(record arrow.Record) error {
+	if s.key >= 0 {
+		return nil
+	}
+	s.key = columnIndex(getHeader(record), s.column)
+	if s.key < 0 {
+		return fmt.Errorf("unknown --subtotal-by column %q", s.column)
+	}
+	s.kinds = make([]sumKind, record.NumCols())
+	for i, f := range record.Schema().Fields() {
+		switch dt := f.Type.(type) {
+		case *arrow.Decimal128Type:
+			s.kinds[i] = sumKind{numeric: true, scale: dt.Scale}
+		case *arrow.Decimal256Type:
+			s.kinds[i] = sumKind{numeric: true, scale: dt.Scale}
+		default:
+			id := f.Type.ID()
+			s.kinds[i] = sumKind{numeric: arrow.IsInteger(id) || arrow.IsFloating(id), float: arrow.IsFloating(id)}
+		}
+	}
+	s.kinds[s.key] = sumKind{}
+	s.groupSums, s.totalSums = s.zero(), s.zero()
+	return nil
+}
+
+func (s *subtotals) zero() []*big.Rat {
+	sums := make([]*big.Rat, len(s.kinds))
+	for i := range sums {
+		sums[i] = new(big.Rat)
+	}
+	return sums
+}
+
+// add returns the rendered rows, with a subtotal row before each row starting a new group.
+func (s *subtotals) add(rows [][]string) [][]string {
+	out := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		if s.groupRows > 0 && row[s.key] != s.group {
+			out = append(out, s.sumRow("Subtotal "+s.group, s.groupRows, s.groupSums))
+			s.groupSums, s.groupRows = s.zero(), 0
+		}
+		s.group = row[s.key]
+		s.groupRows++
+		s.totalRows++
+		for i, k := range s.kinds {
+			if !k.numeric {
+				continue
+			}
+			// NULLs, NaNs and infinities are skipped
+			if v, ok := new(big.Rat).SetString(row[i]); ok {
+				s.groupSums[i].Add(s.groupSums[i], v)
+				s.totalSums[i].Add(s.totalSums[i], v)
+			}
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+// flush returns the subtotal row of the last group, and the grand total row.
+func (s *subtotals) flush() [][]string {
+	if s.totalRows == 0 {
+		return nil
+	}
+	return [][]string{
+		s.sumRow("Subtotal "+s.group, s.groupRows, s.groupSums),
+		s.sumRow("Total", s.totalRows, s.totalSums),
+	}
+}
+
+func (s *subtotals) sumRow(label string, rows int64, sums []*big.Rat) []string {
+	row := make([]string, len(s.kinds))
+	row[s.key] = fmt.Sprintf("%s (%d rows)", label, rows)
+	for i, k := range s.kinds {
+		switch {
+		case !k.numeric:
+		case k.float:
+			f, _ := sums[i].Float64()
+			row[i] = strconv.FormatFloat(f, 'g', -1, 64)
+		case k.scale > 0:
+			row[i] = sums[i].FloatString(int(k.scale))
+		default:
+			row[i] = sums[i].FloatString(0)
+		}
+	}
+	return row
+}
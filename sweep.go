@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"github.com/apache/arrow/go/v15/arrow/memory"
+	"github.com/olekukonko/tablewriter"
+	"google.golang.org/grpc/metadata"
+)
+
+// SweepCmd runs the same query against many databases, concatenating the results.
+type SweepCmd struct {
+	Query           string   `arg:"" help:"Query text"`
+	Databases       []string `short:"d" help:"Databases to query (default: those listed in --databases-file, or else the catalogs of the server)"`
+	DatabasesFile   *os.File `placeholder:"FILE" help:"File with one database per line (blank lines and # comments are skipped)"`
+	Output          string   `short:"o" type:"path" optional:"" help:"filename where output is printed"`
+	Format          string   `enum:",table,json,ndjson,csv,tsv,parquet,arrow,xlsx" default:"" help:"Output format: table, json, ndjson, csv, tsv, parquet, arrow or xlsx (default: inferred from the -o extension, otherwise table)"`
+	ContinueOnError bool     `help:"Keep going when the query fails on a database, reporting the failures at the end"`
+}
+
+func (cmd *SweepCmd) Run(cli *Context) error {
+	format := cmd.Format
+	if format == "" {
+		format = formatForFile(cmd.Output)
+	}
+	if binaryFormats[format] && cmd.Output == "" {
+		return fmt.Errorf("%s output requires an output file (-o)", format)
+	}
+
+	ctx, err := cli.newContext()
+	if err != nil {
+		return err
+	}
+	defer closeReferrals(ctx)
+	c, err := cli.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	databases := cmd.Databases
+	if cmd.DatabasesFile != nil {
+		lines, err := readDatabases(cmd.DatabasesFile)
+		if err != nil {
+			return err
+		}
+		databases = append(databases, lines...)
+	}
+	if len(databases) == 0 {
+		if databases, err = listCatalogs(ctx, c); err != nil {
+			return fmt.Errorf("discovering databases: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Sweeping the %d catalogs of the server\n", len(databases))
+	}
+
+	w := os.Stdout
+	if cmd.Output != "" {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	out, err := newResultWriter(w, printOptions{Format: format})
+	if err != nil {
+		return err
+	}
+
+	sweepErr := runSweep(ctx, out, c, cmd.Query, databases, cmd.ContinueOnError)
+	if cmd.Output != "" {
+		if err := w.Close(); err != nil && sweepErr == nil {
+			return err
+		}
+	}
+	return sweepErr
+}
+
+// runSweep runs query against each database in turn, writing the results to out with an extra leading
+// database column, then closes out and prints a table of per-database timings. All the databases must
+// return the same schema. Unless continueOnError is set, it stops at the first failure.
+func runSweep(ctx context.Context, out resultWriter, c *flightsql.Client, query string, databases []string, continueOnError bool) error {
+	var (
+		schema *arrow.Schema
+		failed int
+	)
+	table := newStatsTable("Database", "Rows", "Execute", "DoGet", "Total", "Status")
+	table.SetAutoWrapText(false)
+	table.SetColumnAlignment([]int{tablewriter.ALIGN_LEFT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT,
+		tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_RIGHT, tablewriter.ALIGN_LEFT})
+	for _, db := range databases {
+		var rows int64
+		t, err := forEachDatabaseRecord(ctx, c, db, query, func(record arrow.Record) error {
+			tagged := withDatabaseColumn(record, db)
+			defer tagged.Release()
+			if schema == nil {
+				schema = tagged.Schema()
+			} else if !schema.Equal(tagged.Schema()) {
+				return fmt.Errorf("result schema differs from the one of the previous databases")
+			}
+			rows += record.NumRows()
+			return out.WriteRecord(tagged)
+		})
+		status := "ok"
+		if err != nil {
+			status = err.Error()
+			failed++
+		}
+		table.Append([]string{db, strconv.FormatInt(rows, 10),
+			t.Execute.String(), t.DoGet.String(), t.Total().String(), status})
+		if err != nil && !continueOnError {
+			fmt.Fprintf(os.Stderr, "Sweep stopped at database %s\n", db)
+			break
+		}
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	fmt.Println()
+	table.Render()
+
+	if failed > 0 {
+		return fmt.Errorf("query failed on %d of %d databases", failed, len(databases))
+	}
+	return nil
+}
+
+// forEachDatabaseRecord executes query against db and calls fn for each record batch received.
+func forEachDatabaseRecord(ctx context.Context, c *flightsql.Client, db, query string, fn func(arrow.Record) error) (Timings, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set("database", db)
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	beforeExecute := time.Now()
+	info, err := c.Execute(ctx, query)
+	if err != nil {
+		return Timings{}, err
+	}
+	executeDuration := time.Since(beforeExecute)
+	timings, err := fetchRecords(ctx, c, info, executor(c, query), fn)
+	return timings.Add(Timings{Execute: executeDuration}), err
+}
+
+// withDatabaseColumn returns record with a leading database column holding db.
+func withDatabaseColumn(record arrow.Record, db string) arrow.Record {
+	b := array.NewStringBuilder(memory.DefaultAllocator)
+	defer b.Release()
+	for i := int64(0); i < record.NumRows(); i++ {
+		b.Append(db)
+	}
+	col := b.NewArray()
+	defer col.Release()
+
+	fields := append([]arrow.Field{{Name: "database", Type: arrow.BinaryTypes.String}}, record.Schema().Fields()...)
+	cols := append([]arrow.Array{col}, record.Columns()...)
+	metadata := record.Schema().Metadata()
+	return array.NewRecord(arrow.NewSchema(fields, &metadata), cols, record.NumRows())
+}
+
+// readDatabases reads one database name per line, skipping blank lines and # comments.
+func readDatabases(f *os.File) ([]string, error) {
+	defer f.Close()
+	var databases []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		databases = append(databases, line)
+	}
+	return databases, scanner.Err()
+}
+
+// listCatalogs returns the names of the catalogs of the server.
+func listCatalogs(ctx context.Context, c *flightsql.Client) ([]string, error) {
+	info, err := c.GetCatalogs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var catalogs []string
+	_, err = forEachRecord(ctx, c, info, func(record arrow.Record) error {
+		names, ok := record.Column(0).(*array.String)
+		if !ok {
+			return fmt.Errorf("unexpected catalog_name column type %s", record.Column(0).DataType())
+		}
+		for i := 0; i < names.Len(); i++ {
+			catalogs = append(catalogs, names.Value(i))
+		}
+		return nil
+	})
+	return catalogs, err
+}
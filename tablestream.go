@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// measure widens t.widths to fit rows, as tablewriter would lay them out.
+func (t *tableWriter) measure(rows [][]string) {
+	for _, row := range rows {
+		if len(t.widths) < len(row) {
+			t.widths = append(t.widths, make([]int, len(row)-len(t.widths))...)
+		}
+		for i, cell := range row {
+			if w := cellWidth(cell); w > t.widths[i] {
+				t.widths[i] = w
+			}
+		}
+	}
+}
+
+// cellWidth returns the width of a table cell, wrapped like tablewriter does.
+func cellWidth(cell string) int {
+	width := 0
+	for _, line := range strings.Split(cell, "\n") {
+		w := tablewriter.DisplayWidth(line)
+		if w > tablewriter.MAX_ROW_WIDTH {
+			// wrapped at word boundaries, but long words aren't broken
+			w = tablewriter.MAX_ROW_WIDTH
+			for _, word := range strings.Fields(line) {
+				if ww := tablewriter.DisplayWidth(word); ww > w {
+					w = ww
+				}
+			}
+		}
+		if w > width {
+			width = w
+		}
+	}
+	return width
+}
+
+// startStreaming prints the rows buffered so far as a table, and switches to streaming the following rows,
+// so that memory doesn't grow with the number of rows.
+func (t *tableWriter) startStreaming() {
+	if t.opts.ShowTypes {
+		t.measure([][]string{t.types})
+	}
+	t.measure([][]string{t.header})
+	t.setHeader()
+	t.table.Render()
+	t.streaming = true
+}
+
+// streamRows prints rows right away, in a table without header whose columns are at least as wide
+// as the ones printed before. Columns only get misaligned if later values are wider than all the previous ones.
+func (t *tableWriter) streamRows(rows [][]string) {
+	t.measure(rows)
+	table := t.streamTable()
+	table.AppendBulk(rows)
+	table.Render()
+}
+
+func (t *tableWriter) streamTable() *tablewriter.Table {
+	table := newResultTable(t.w)
+	for i, w := range t.widths {
+		table.SetColMinWidth(i, w)
+	}
+	return table
+}
+
+// closeStream prints the footer of a streamed table, if any.
+func (t *tableWriter) closeStream() {
+	table := t.streamTable()
+	t.setFooter(table)
+	table.Render()
+}
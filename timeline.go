@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/array"
+)
+
+// timelineMaxBuckets bounds the number of lines printed by --timeline.
+const timelineMaxBuckets = 1000
+
+// timeline counts the rows per interval of a time column, printed as a bar chart after the results.
+// Intervals without rows are printed too, making gaps visible.
+type timeline struct {
+	column   string
+	interval time.Duration
+	zone     string
+
+	counts map[int64]int64
+	nulls  int64
+}
+
+// parseTimeline parses a --timeline argument of the form column:interval, where interval is a duration
+// like 5m or 1h, or a number of days like 1d.
+func parseTimeline(s string) (*timeline, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("invalid --timeline %q: expecting COLUMN:INTERVAL", s)
+	}
+	interval, err := parseInterval(s[i+1:])
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("invalid --timeline interval %q", s[i+1:])
+	}
+	return &timeline{column: s[:i], interval: interval, counts: map[int64]int64{}}, nil
+}
+
+func parseInterval(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		return time.Duration(n) * 24 * time.Hour, err
+	}
+	return time.ParseDuration(s)
+}
+
+func (t *timeline) add(record arrow.Record) error {
+	c := columnIndex(getHeader(record), t.column)
+	if c < 0 {
+		return fmt.Errorf("unknown --timeline column %q", t.column)
+	}
+	col := record.Column(c)
+	if tt, ok := col.DataType().(*arrow.TimestampType); ok {
+		t.zone = tt.TimeZone
+	}
+	for r := 0; r < col.Len(); r++ {
+		if col.IsNull(r) {
+			t.nulls++
+			continue
+		}
+		ts, err := timeValue(col, r)
+		if err != nil {
+			return fmt.Errorf("--timeline column %q: %w", t.column, err)
+		}
+		t.counts[ts.Truncate(t.interval).UnixNano()]++
+	}
+	return nil
+}
+
+// timeValue returns a value of a timestamp or date column, or of a string column holding timestamps.
+func timeValue(col arrow.Array, row int) (time.Time, error) {
+	switch col := col.(type) {
+	case *array.Timestamp:
+		return col.Value(row).ToTime(col.DataType().(*arrow.TimestampType).Unit), nil
+	case *array.Date32:
+		return col.Value(row).ToTime(), nil
+	case *array.Date64:
+		return col.Value(row).ToTime(), nil
+	case *array.String:
+		for _, layout := range []string{time.RFC3339Nano, pgTimestampFormat, "2006-01-02"} {
+			if ts, err := time.Parse(layout, col.Value(row)); err == nil {
+				return ts, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as a timestamp", col.Value(row))
+	}
+	return time.Time{}, fmt.Errorf("expecting a timestamp, got %s", col.DataType())
+}
+
+func (t *timeline) print(w io.Writer, opts renderOptions) error {
+	var rows int64
+	first, last := int64(0), int64(0)
+	for bucket, n := range t.counts {
+		if rows == 0 || bucket < first {
+			first = bucket
+		}
+		if rows == 0 || bucket > last {
+			last = bucket
+		}
+		rows += n
+	}
+	fmt.Fprintf(w, "%s per %s: %d rows, %d NULLs\n", t.column, t.interval, rows, t.nulls)
+	if rows == 0 {
+		return nil
+	}
+	if n := (last-first)/int64(t.interval) + 1; n > timelineMaxBuckets {
+		return fmt.Errorf("--timeline would print %d intervals of %s, use a longer interval", n, t.interval)
+	}
+
+	var bars []histBar
+	for bucket := first; bucket <= last; bucket += int64(t.interval) {
+		label := opts.renderTimestamp(time.Unix(0, bucket).UTC(), t.zone)
+		bars = append(bars, histBar{label: label, count: t.counts[bucket]})
+	}
+	printBars(w, bars)
+	return nil
+}
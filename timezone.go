@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// typeZones caches the locations of the time zones named in timestamp types.
+var typeZones sync.Map
+
+// loadTimeZone returns the location of a --tz zone: local, UTC or an IANA name like Europe/Rome.
+func loadTimeZone(name string) (*time.Location, error) {
+	if strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --tz: %w", err)
+	}
+	return loc, nil
+}
+
+// typeZone returns the location of the time zone of a timestamp type, which is either a name
+// or a fixed offset like +07:30, or nil if it's empty or unknown.
+func typeZone(zone string) *time.Location {
+	if zone == "" {
+		return nil
+	}
+	if loc, ok := typeZones.Load(zone); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		if t, err := time.Parse("-07:00", zone); err == nil {
+			loc = t.Location()
+		} else {
+			loc = nil
+		}
+	}
+	typeZones.Store(zone, loc)
+	return loc
+}
+
+// renderTimestamp renders t in the --tz zone, or else in the zone of its type (if any).
+func (o renderOptions) renderTimestamp(t time.Time, zone string) string {
+	if loc := o.TimeZone; loc != nil {
+		t = t.In(loc)
+	} else if loc := typeZone(zone); loc != nil {
+		t = t.In(loc)
+	}
+	if o.ShowTZOffset {
+		return t.Format(pgTimestampFormat + "-07:00")
+	}
+	return t.Format(pgTimestampFormat)
+}
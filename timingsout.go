@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/util"
+)
+
+// resultCounter counts the rows and bytes (of Arrow data) of the results.
+type resultCounter struct {
+	rows  int64
+	bytes int64
+}
+
+func (c *resultCounter) add(record arrow.Record) error {
+	c.rows += record.NumRows()
+	c.bytes += int64(util.TotalRecordSize(record))
+	return nil
+}
+
+// writeTimings writes the timings of a query, as the usual summary line or, with format json, as e.g.:
+//
+//	{"warmup_seconds":0.0012,"execute_seconds":0.0003,"doget_seconds":0.0021,"total_seconds":0.0036,"rows":100,"bytes":6400}
+func writeTimings(w io.Writer, format string, timings Timings, counts *resultCounter) error {
+	if format != "json" {
+		_, err := fmt.Fprint(w, timings)
+		return err
+	}
+	b, err := json.Marshal(struct {
+		Warmup  float64 `json:"warmup_seconds"`
+		Execute float64 `json:"execute_seconds"`
+		DoGet   float64 `json:"doget_seconds"`
+		Total   float64 `json:"total_seconds"`
+		Rows    int64   `json:"rows"`
+		Bytes   int64   `json:"bytes"`
+	}{
+		Warmup:  timings.Warmup.Seconds(),
+		Execute: timings.Execute.Seconds(),
+		DoGet:   timings.DoGet.Seconds(),
+		Total:   timings.Total().Seconds(),
+		Rows:    counts.rows,
+		Bytes:   counts.bytes,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsConfig returns the TLS configuration for https URLs, trusting the --cacert CA and presenting
+// the --cert/--key client certificate, if given.
+func (cli *CLI) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         cli.TLSServerName,
+		InsecureSkipVerify: cli.InsecureSkipVerify,
+	}
+	if cli.CACert != "" {
+		pem, err := os.ReadFile(cli.CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no PEM certificates found", cli.CACert)
+		}
+		cfg.RootCAs = pool
+	}
+	if (cli.Cert == "") != (cli.Key == "") {
+		return nil, fmt.Errorf("--cert and --key must be given together")
+	}
+	if cli.Cert != "" {
+		cert, err := tls.LoadX509KeyPair(cli.Cert, cli.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	tokenExchangeGrantType   = "urn:ietf:params:oauth:grant-type:token-exchange"
+	accessTokenType          = "urn:ietf:params:oauth:token-type:access_token"
+	tokenExchangeHTTPTimeout = 30 * time.Second
+)
+
+// tokenExchangeResponse is the successful response of an RFC 8693 token exchange.
+type tokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// tokenExchangeError is the error response of an OAuth 2.0 endpoint.
+type tokenExchangeError struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchangedToken is the access token obtained by exchanging the subject token.
+type exchangedToken struct {
+	subject string
+	token   string
+	expiry  time.Time
+}
+
+// exchangeToken trades the configured token for a short-lived access token at cli.TokenExchangeURL,
+// following the OAuth 2.0 token exchange flow (RFC 8693). The configured token is kept, and the access token
+// is reused until it's about to expire, so that retries and other connections don't exchange it again.
+func (cli *CLI) exchangeToken() error {
+	if cli.TokenExchangeURL == "" {
+		return nil
+	}
+	if cli.Token == "" {
+		return fmt.Errorf("token exchange: no token to exchange")
+	}
+	if t := cli.exchanged; t != nil && t.subject == cli.Token &&
+		(t.expiry.IsZero() || time.Now().Add(oauthRefreshMargin).Before(t.expiry)) {
+		return nil
+	}
+
+	form := url.Values{
+		"grant_type":           {tokenExchangeGrantType},
+		"subject_token":        {cli.Token},
+		"subject_token_type":   {accessTokenType},
+		"requested_token_type": {accessTokenType},
+	}
+	if cli.TokenExchangeAudience != "" {
+		form.Set("audience", cli.TokenExchangeAudience)
+	}
+
+	r, err := requestToken(cli.TokenExchangeURL, form)
+	if err != nil {
+		return fmt.Errorf("token exchange: %w", err)
+	}
+	t := &exchangedToken{subject: cli.Token, token: r.AccessToken}
+	if r.ExpiresIn > 0 {
+		t.expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	cli.exchanged = t
+	return nil
+}
+
+// accessToken returns the token sent to the server: the exchanged one with --token-exchange-url, otherwise --token.
+func (cli *CLI) accessToken() string {
+	if cli.TokenExchangeURL != "" && cli.exchanged != nil {
+		return cli.exchanged.token
+	}
+	return cli.Token
+}
+
+// requestToken posts form to the OAuth 2.0 token endpoint and returns the access token it issues.
+func requestToken(endpoint string, form url.Values) (tokenExchangeResponse, error) {
+	client := &http.Client{Timeout: tokenExchangeHTTPTimeout}
+	resp, err := client.Post(endpoint, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokenExchangeResponse{}, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return tokenExchangeResponse{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var e tokenExchangeError
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			if e.ErrorDescription != "" {
+				return tokenExchangeResponse{}, fmt.Errorf("%s: %s", e.Error, e.ErrorDescription)
+			}
+			return tokenExchangeResponse{}, fmt.Errorf("%s", e.Error)
+		}
+		return tokenExchangeResponse{}, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var r tokenExchangeResponse
+	if err := json.Unmarshal(body, &r); err != nil {
+		return tokenExchangeResponse{}, fmt.Errorf("parsing response: %w", err)
+	}
+	if r.AccessToken == "" {
+		return tokenExchangeResponse{}, fmt.Errorf("response has no access_token")
+	}
+	return r, nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// traceparentHeader and tracestateHeader carry the W3C trace context (https://www.w3.org/TR/trace-context/).
+const (
+	traceparentHeader = "traceparent"
+	tracestateHeader  = "tracestate"
+)
+
+var traceparentRE = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+
+// traceContext is the trace the requests belong to: a new one with --gen-trace-id, or the one
+// of the caller, from the TRACEPARENT and TRACESTATE environment variables.
+type traceContext struct {
+	traceID string
+	// parentID is the span of the caller, if any
+	parentID string
+	flags    string
+	state    string
+}
+
+// parseTraceparent parses a W3C traceparent value, rejecting the invalid all-zero IDs.
+func parseTraceparent(s string) (*traceContext, error) {
+	m := traceparentRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || m[1] == "ff" || strings.Trim(m[2], "0") == "" || strings.Trim(m[3], "0") == "" {
+		return nil, fmt.Errorf("invalid traceparent %q", s)
+	}
+	return &traceContext{traceID: m[2], parentID: m[3], flags: m[4]}, nil
+}
+
+// traceFromEnv returns the trace context of the caller, or nil if there's none.
+// An invalid TRACEPARENT is ignored with a warning, rather than failing the query.
+func traceFromEnv() *traceContext {
+	s := os.Getenv("TRACEPARENT")
+	if s == "" {
+		return nil
+	}
+	tc, err := parseTraceparent(s)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ignoring TRACEPARENT: %v\n", err)
+		return nil
+	}
+	tc.state = os.Getenv("TRACESTATE")
+	return tc
+}
+
+// traceHeaders returns the trace headers of a request, in the formats selected by --trace-format,
+// each request being a new span of the trace.
+func (cli *CLI) traceHeaders() (headers []string, traceID string) {
+	tc := traceFromEnv()
+	if tc == nil {
+		if !cli.GenTraceId {
+			return nil, ""
+		}
+		tc = &traceContext{traceID: generateRandomHex(16), flags: "01"}
+	}
+	spanID := generateRandomHex(8)
+
+	if cli.TraceFormat != "w3c" {
+		parentID := tc.parentID
+		if parentID == "" {
+			parentID = "0"
+		}
+		flags := strings.TrimLeft(tc.flags, "0")
+		if flags == "" {
+			flags = "0"
+		}
+		uber := fmt.Sprintf("%s:%s:%s:%s", tc.traceID, spanID, parentID, flags)
+		headers = append(headers, traceIDHeader, uber, traceIDHeader2, uber)
+	}
+	if cli.TraceFormat != "uber" {
+		headers = append(headers, traceparentHeader, fmt.Sprintf("00-%s-%s-%s", tc.traceID, spanID, tc.flags))
+		if tc.state != "" {
+			headers = append(headers, tracestateHeader, tc.state)
+		}
+	}
+	return headers, tc.traceID
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow"
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+)
+
+// maxVerifyDiffRows limits how many differing rows are shown for each nondeterministic run.
+const maxVerifyDiffRows = 5
+
+// verifyRun is the canonical (sorted) result of a single run of the query.
+type verifyRun struct {
+	header []string
+	rows   [][]string
+	digest string
+}
+
+// verifyQuery runs query n times and checks that all runs return the same results.
+// Rows are sorted before comparing, so that a different row order doesn't count as a difference.
+func verifyQuery(ctx context.Context, w io.Writer, c *flightsql.Client, query string, opts renderOptions, n int) (Timings, error) {
+	var (
+		timings Timings
+		first   verifyRun
+		differ  int
+	)
+	for i := 1; i <= n; i++ {
+		run, t, err := canonicalRun(ctx, c, query, opts)
+		if err != nil {
+			return timings, fmt.Errorf("run %d: %w", i, err)
+		}
+		timings.Add(t)
+
+		status := "ok"
+		if i == 1 {
+			first = run
+		} else if run.digest != first.digest {
+			status = "DIFFERS"
+			differ++
+		}
+		fmt.Fprintf(w, "run %d: %d rows, digest %s, %s\n", i, len(run.rows), run.digest[:12], status)
+		if status != "ok" {
+			writeRowDiff(w, first, run)
+		}
+	}
+
+	if differ > 0 {
+		return timings, fmt.Errorf("nondeterministic results: %d of %d runs differ from the first one", differ, n)
+	}
+	fmt.Fprintf(w, "all %d runs returned identical results\n", n)
+	return timings, nil
+}
+
+// canonicalRun executes query and returns its rendered rows in sorted order.
+func canonicalRun(ctx context.Context, c *flightsql.Client, query string, opts renderOptions) (verifyRun, Timings, error) {
+	beforeExecute := time.Now()
+	info, err := c.Execute(ctx, query)
+	if err != nil {
+		return verifyRun{}, Timings{}, err
+	}
+	executeDuration := time.Since(beforeExecute)
+
+	var run verifyRun
+	timings, err := fetchRecords(ctx, c, info, executor(c, query), func(record arrow.Record) error {
+		if run.header == nil {
+			run.header = getHeader(record)
+		}
+		rows, err := opts.renderRecord(record)
+		if err != nil {
+			return err
+		}
+		run.rows = append(run.rows, rows...)
+		return nil
+	})
+	if err != nil {
+		return verifyRun{}, Timings{}, err
+	}
+	if err := sortRows(run.rows, run.header, nil); err != nil {
+		return verifyRun{}, Timings{}, err
+	}
+
+	h := sha256.New()
+	fmt.Fprintln(h, strings.Join(run.header, "\x1f"))
+	for _, row := range run.rows {
+		fmt.Fprintln(h, strings.Join(row, "\x1f"))
+	}
+	run.digest = fmt.Sprintf("%x", h.Sum(nil))
+
+	return run, timings.Add(Timings{Execute: executeDuration}), nil
+}
+
+// writeRowDiff prints the rows missing from (-) or added to (+) run compared to first.
+func writeRowDiff(w io.Writer, first, run verifyRun) {
+	if strings.Join(first.header, "\x1f") != strings.Join(run.header, "\x1f") {
+		fmt.Fprintf(w, "  - columns: %s\n  + columns: %s\n", strings.Join(first.header, " | "), strings.Join(run.header, " | "))
+		return
+	}
+
+	counts := map[string]int{}
+	for _, row := range first.rows {
+		counts[strings.Join(row, " | ")]++
+	}
+	var added []string
+	for _, row := range run.rows {
+		key := strings.Join(row, " | ")
+		if counts[key] > 0 {
+			counts[key]--
+		} else {
+			added = append(added, key)
+		}
+	}
+	var removed []string
+	for _, row := range first.rows {
+		key := strings.Join(row, " | ")
+		if counts[key] > 0 {
+			counts[key]--
+			removed = append(removed, key)
+		}
+	}
+
+	writeDiffLines(w, "-", removed)
+	writeDiffLines(w, "+", added)
+}
+
+func writeDiffLines(w io.Writer, sign string, lines []string) {
+	for i, line := range lines {
+		if i == maxVerifyDiffRows {
+			fmt.Fprintf(w, "  %s ... and %d more rows\n", sign, len(lines)-i)
+			return
+		}
+		fmt.Fprintf(w, "  %s %s\n", sign, line)
+	}
+}
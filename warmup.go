@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/apache/arrow/go/v15/arrow/flight/flightsql"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// warmup sends a dummy request (GetCatalogs) on c, so that the time spent on the first flight request
+// of a connection, whatever that request is, doesn't count against the requests we measure.
+//
+// With --warmup=auto, each connection is warmed up only once, before its first query, and a server
+// not implementing GetCatalogs just goes without. With --warmup=always every call warms up and any
+// failure is an error; --warmup=never turns it off.
+func (cli *CLI) warmup(ctx context.Context, c *flightsql.Client) (time.Duration, error) {
+	if cli.Warmup == "never" || (cli.Warmup == "auto" && cli.warmed[c]) {
+		return 0, nil
+	}
+	phasesFrom(ctx).enter("warmup")
+	start := time.Now()
+	_, err := c.GetCatalogs(ctx)
+	if err != nil && (cli.Warmup != "auto" || status.Code(err) != codes.Unimplemented) {
+		return 0, fmt.Errorf("%w: %w", errWarmup, err)
+	}
+	if cli.warmed == nil {
+		cli.warmed = map[*flightsql.Client]bool{}
+	}
+	cli.warmed[c] = true
+	return time.Since(start), nil
+}
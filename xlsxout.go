@@ -0,0 +1,115 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/apache/arrow/go/v15/arrow"
+)
+
+// xlsxWriter writes the results as a single sheet Excel workbook. Numeric columns are written as
+// numbers, everything else as text. The sheet is kept in memory until Close.
+type xlsxWriter struct {
+	w      io.Writer
+	opts   printOptions
+	header bool
+	sheet  bytes.Buffer
+}
+
+func newXLSXWriter(w io.Writer, opts printOptions) *xlsxWriter {
+	return &xlsxWriter{w: w, opts: opts, header: !opts.NoHeader}
+}
+
+func (x *xlsxWriter) WriteRecord(record arrow.Record) error {
+	if x.header {
+		x.sheet.WriteString("<row>")
+		for _, name := range getHeader(record) {
+			x.writeString(name)
+		}
+		x.sheet.WriteString("</row>")
+		x.header = false
+	}
+
+	schema := record.Schema()
+	for r := 0; r < int(record.NumRows()); r++ {
+		x.sheet.WriteString("<row>")
+		for col := 0; col < int(record.NumCols()); col++ {
+			column := record.Column(col)
+			if column.IsNull(r) {
+				x.sheet.WriteString("<c/>")
+				continue
+			}
+			s, err := x.opts.renderColumn(schema.Field(col), column, r)
+			if err != nil {
+				return err
+			}
+			if isXLSXNumber(column.DataType(), s) {
+				x.sheet.WriteString(`<c t="n"><v>`)
+				xml.EscapeText(&x.sheet, []byte(s))
+				x.sheet.WriteString("</v></c>")
+			} else {
+				x.writeString(s)
+			}
+		}
+		x.sheet.WriteString("</row>")
+	}
+	return nil
+}
+
+// isXLSXNumber reports whether a rendered value of a column of type dt can be stored as a number.
+func isXLSXNumber(dt arrow.DataType, s string) bool {
+	if id := dt.ID(); !arrow.IsInteger(id) && !arrow.IsFloating(id) && !arrow.IsDecimal(id) {
+		return false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	return err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func (x *xlsxWriter) writeString(s string) {
+	x.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+	xml.EscapeText(&x.sheet, []byte(s))
+	x.sheet.WriteString("</t></is></c>")
+}
+
+func (x *xlsxWriter) Close() error {
+	z := zip.NewWriter(x.w)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>` + x.sheet.String() + "</sheetData></worksheet>"},
+	}
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+const (
+	xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Results" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+)